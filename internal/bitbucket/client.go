@@ -0,0 +1,351 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+// pageSize is the number of items requested per page
+const pageSize = 100
+
+// Client lists pull requests of Bitbucket Server or Data Center through the
+// REST API (1.0). Owners are project keys, or ~username for personal
+// repositories, and repositories are slugs.
+type Client struct {
+	baseURL string // API root, e.g. https://bitbucket.example.com/rest/api/1.0/
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for a bitbucket source
+func NewClient(cfg config.SourceConfig) (*Client, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("Bitbucket token is required")
+	}
+	if cfg.BaseURL == "" {
+		return nil, errors.New("Bitbucket base URL is required")
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/") + "/rest/api/1.0/",
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// APIError is a non-2xx response of the Bitbucket API
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Bitbucket API returned %d", e.Status)
+	}
+	return fmt.Sprintf("Bitbucket API returned %d: %s", e.Status, e.Message)
+}
+
+// get fetches path and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+		apiErr := &APIError{Status: resp.StatusCode}
+		if len(body.Errors) > 0 {
+			apiErr.Message = body.Errors[0].Message
+		}
+		return apiErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid Bitbucket API response for %s: %w", path, err)
+	}
+	return nil
+}
+
+// page is the envelope of paginated Bitbucket responses
+type page[T any] struct {
+	Values        []T  `json:"values"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+// listAll fetches every page of a paginated endpoint
+func listAll[T any](ctx context.Context, c *Client, path string, query url.Values) ([]T, error) {
+	q := url.Values{"limit": {strconv.Itoa(pageSize)}}
+	for k, v := range query {
+		q[k] = v
+	}
+
+	var all []T
+	for start := 0; ; {
+		q.Set("start", strconv.Itoa(start))
+		var p page[T]
+		if err := c.get(ctx, path, q, &p); err != nil {
+			return nil, err
+		}
+		all = append(all, p.Values...)
+		if p.IsLastPage || len(p.Values) == 0 {
+			return all, nil
+		}
+		start = p.NextPageStart
+	}
+}
+
+type pullRequest struct {
+	ID           int           `json:"id"`
+	Title        string        `json:"title"`
+	State        string        `json:"state"`
+	Draft        bool          `json:"draft"` // Bitbucket 8.18 and later
+	CreatedDate  int64         `json:"createdDate"`
+	UpdatedDate  int64         `json:"updatedDate"`
+	Author       participant   `json:"author"`
+	Reviewers    []participant `json:"reviewers"`
+	Participants []participant `json:"participants"`
+	FromRef      ref           `json:"fromRef"`
+	ToRef        ref           `json:"toRef"`
+	Links        struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+type participant struct {
+	User struct {
+		Name         string `json:"name"`
+		Slug         string `json:"slug"`
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+	} `json:"user"`
+	Role   string `json:"role"`   // AUTHOR, REVIEWER or PARTICIPANT
+	Status string `json:"status"` // APPROVED, NEEDS_WORK or UNAPPROVED
+}
+
+type ref struct {
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit"`
+}
+
+type diffResponse struct {
+	Diffs []struct {
+		Hunks []struct {
+			Segments []struct {
+				Type  string            `json:"type"` // ADDED, REMOVED or CONTEXT
+				Lines []json.RawMessage `json:"lines"`
+			} `json:"segments"`
+		} `json:"hunks"`
+	} `json:"diffs"`
+}
+
+func repoPath(owner, repo string) string {
+	if user, ok := strings.CutPrefix(owner, "~"); ok {
+		return "users/" + url.PathEscape(user) + "/repos/" + url.PathEscape(repo)
+	}
+	return "projects/" + url.PathEscape(owner) + "/repos/" + url.PathEscape(repo)
+}
+
+// GetPullRequests returns the open pull requests of repos of owner
+func (c *Client) GetPullRequests(ctx context.Context, owner string, repos []string) ([]*forge.PullRequest, error) {
+	var (
+		prs    []*forge.PullRequest
+		failed forge.FetchErrors
+	)
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return prs, err
+		}
+		repoPRs, err := c.getPullRequestsForRepo(ctx, owner, repo)
+		if err != nil {
+			failed = append(failed, &forge.RepoError{Owner: owner, Repo: repo, Err: err})
+			continue
+		}
+		prs = append(prs, repoPRs...)
+	}
+	if len(failed) > 0 {
+		return prs, failed
+	}
+	return prs, nil
+}
+
+func (c *Client) getPullRequestsForRepo(ctx context.Context, owner, repo string) ([]*forge.PullRequest, error) {
+	open, err := listAll[pullRequest](ctx, c, repoPath(owner, repo)+"/pull-requests", url.Values{"state": {"OPEN"}})
+	if err != nil {
+		return nil, err
+	}
+
+	prs := make([]*forge.PullRequest, 0, len(open))
+	for _, pr := range open {
+		converted, err := c.newPullRequest(ctx, owner, repo, pr)
+		if err != nil {
+			return nil, fmt.Errorf("PR #%d: %w", pr.ID, err)
+		}
+		prs = append(prs, converted)
+	}
+	return prs, nil
+}
+
+// GetPRDetails returns a single pull request
+func (c *Client) GetPRDetails(ctx context.Context, owner, repo string, number int) (*forge.PullRequest, error) {
+	var pr pullRequest
+	if err := c.get(ctx, fmt.Sprintf("%s/pull-requests/%d", repoPath(owner, repo), number), nil, &pr); err != nil {
+		return nil, err
+	}
+	return c.newPullRequest(ctx, owner, repo, pr)
+}
+
+// newPullRequest maps a Bitbucket pull request onto the forge model.
+// Reviewers and participants who approved become APPROVED reviews and those
+// who marked it as needing work CHANGES_REQUESTED ones; reviewers who have
+// not acted yet are the requested reviewers. Bitbucket does not report when
+// a participant's status was set, so the reviews carry no time.
+func (c *Client) newPullRequest(ctx context.Context, owner, repo string, pr pullRequest) (*forge.PullRequest, error) {
+	var (
+		reviews   []forge.Review
+		requested []string
+	)
+	for _, p := range append(append([]participant{}, pr.Reviewers...), pr.Participants...) {
+		switch p.Status {
+		case "APPROVED":
+			reviews = append(reviews, forge.Review{User: p.User.Name, State: "APPROVED"})
+		case "NEEDS_WORK":
+			reviews = append(reviews, forge.Review{User: p.User.Name, State: "CHANGES_REQUESTED"})
+		default:
+			if p.Role == "REVIEWER" {
+				requested = append(requested, p.User.Name)
+			}
+		}
+	}
+
+	additions, deletions, files, err := c.diffStats(ctx, fmt.Sprintf("%s/pull-requests/%d", repoPath(owner, repo), pr.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff stats: %w", err)
+	}
+
+	var link string
+	if len(pr.Links.Self) > 0 {
+		link = pr.Links.Self[0].Href
+	}
+
+	result := &forge.PullRequest{
+		Number:    pr.ID,
+		Title:     pr.Title,
+		State:     strings.ToLower(pr.State),
+		Draft:     pr.Draft,
+		CreatedAt: time.UnixMilli(pr.CreatedDate),
+		UpdatedAt: time.UnixMilli(pr.UpdatedDate),
+		User: &forge.User{
+			Login: pr.Author.User.Name,
+			Email: pr.Author.User.EmailAddress,
+			Name:  pr.Author.User.DisplayName,
+		},
+		Head:               &forge.Branch{Ref: pr.FromRef.DisplayID, SHA: pr.FromRef.LatestCommit},
+		Base:               &forge.Branch{Ref: pr.ToRef.DisplayID, SHA: pr.ToRef.LatestCommit},
+		URL:                link,
+		Repo:               repo,
+		Owner:              owner,
+		RequestedReviewers: requested,
+	}
+	result.SetReviews(reviews)
+	result.SetSize(additions, deletions, files)
+	return result, nil
+}
+
+// diffStats counts the added and removed lines and the changed files of a
+// pull request from its diff, fetched without context lines
+func (c *Client) diffStats(ctx context.Context, prPath string) (additions, deletions, files int, err error) {
+	var diff diffResponse
+	query := url.Values{"contextLines": {"0"}, "withComments": {"false"}}
+	if err := c.get(ctx, prPath+"/diff", query, &diff); err != nil {
+		return 0, 0, 0, err
+	}
+	for _, d := range diff.Diffs {
+		files++
+		for _, hunk := range d.Hunks {
+			for _, segment := range hunk.Segments {
+				switch segment.Type {
+				case "ADDED":
+					additions += len(segment.Lines)
+				case "REMOVED":
+					deletions += len(segment.Lines)
+				}
+			}
+		}
+	}
+	return additions, deletions, files, nil
+}
+
+type repository struct {
+	Slug     string `json:"slug"`
+	Public   bool   `json:"public"`
+	Archived bool   `json:"archived"` // Bitbucket 8.0 and later
+	Origin   *struct {
+		Slug string `json:"slug"`
+	} `json:"origin"` // Set for forks
+}
+
+// ListRepositories lists the repositories of a project, or of a user when
+// owner is ~username
+func (c *Client) ListRepositories(ctx context.Context, owner string) ([]*forge.Repository, error) {
+	path := "projects/" + url.PathEscape(owner) + "/repos"
+	if user, ok := strings.CutPrefix(owner, "~"); ok {
+		path = "users/" + url.PathEscape(user) + "/repos"
+	}
+	all, err := listAll[repository](ctx, c, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories of %s: %w", owner, err)
+	}
+
+	repos := make([]*forge.Repository, 0, len(all))
+	for _, r := range all {
+		visibility := "private"
+		if r.Public {
+			visibility = "public"
+		}
+		repos = append(repos, &forge.Repository{
+			Owner:      owner,
+			Name:       r.Slug,
+			Visibility: visibility,
+			Archived:   r.Archived,
+			Fork:       r.Origin != nil,
+		})
+	}
+	return repos, nil
+}
+
+// Ping checks that the API is reachable and the token is accepted
+func (c *Client) Ping(ctx context.Context) error {
+	var p page[json.RawMessage]
+	return c.get(ctx, "projects", url.Values{"limit": {"1"}}, &p)
+}
@@ -0,0 +1,91 @@
+package bitbucket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+)
+
+func TestClient_GetPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	repo := "/rest/api/1.0/projects/PLAT/repos/billing"
+	mux.HandleFunc(repo+"/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer bbt" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("state") != "OPEN" {
+			t.Errorf("state = %q, want OPEN", r.URL.Query().Get("state"))
+		}
+		if r.URL.Query().Get("start") == "0" {
+			w.Write([]byte(`{"isLastPage": false, "nextPageStart": 1, "values": [
+				{"id": 7, "title": "Add invoices", "state": "OPEN", "createdDate": 1714557600000, "updatedDate": 1714644000000,
+				 "author": {"user": {"name": "alice", "displayName": "Alice", "emailAddress": "alice@example.com"}, "role": "AUTHOR"},
+				 "reviewers": [
+					{"user": {"name": "bob"}, "role": "REVIEWER", "status": "APPROVED"},
+					{"user": {"name": "carol"}, "role": "REVIEWER", "status": "UNAPPROVED"}],
+				 "participants": [{"user": {"name": "dave"}, "role": "PARTICIPANT", "status": "NEEDS_WORK"}],
+				 "fromRef": {"displayId": "feature", "latestCommit": "abc123"}, "toRef": {"displayId": "main"},
+				 "links": {"self": [{"href": "https://bitbucket.example.com/projects/PLAT/repos/billing/pull-requests/7"}]}}]}`))
+			return
+		}
+		w.Write([]byte(`{"isLastPage": true, "values": [{"id": 8, "title": "Docs", "state": "OPEN", "draft": true,
+			"author": {"user": {"name": "erin"}}, "fromRef": {"displayId": "docs"}, "toRef": {"displayId": "main"}}]}`))
+	})
+	mux.HandleFunc(repo+"/pull-requests/7/diff", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("contextLines") != "0" {
+			t.Errorf("contextLines = %q, want 0", r.URL.Query().Get("contextLines"))
+		}
+		w.Write([]byte(`{"diffs": [
+			{"hunks": [{"segments": [{"type": "REMOVED", "lines": [{"line": "old"}]}, {"type": "ADDED", "lines": [{"line": "new"}, {"line": "more"}]}]}]},
+			{"hunks": [{"segments": [{"type": "ADDED", "lines": [{"line": "file"}]}]}]}]}`))
+	})
+	mux.HandleFunc(repo+"/pull-requests/8/diff", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"diffs": []}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewClient(config.SourceConfig{BaseURL: server.URL, Token: "bbt", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prs, err := client.GetPullRequests(context.Background(), "PLAT", []string{"billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("got %d PRs, want 2", len(prs))
+	}
+
+	pr := prs[0]
+	if pr.Number != 7 || pr.State != "open" || pr.User.Login != "alice" || pr.Head.SHA != "abc123" || pr.Draft {
+		t.Errorf("unexpected PR %+v", pr)
+	}
+	if !pr.CreatedAt.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("created at = %v, want 2024-05-01 10:00 UTC", pr.CreatedAt)
+	}
+	wantStates := map[string]string{"bob": "APPROVED", "dave": "CHANGES_REQUESTED"}
+	if len(pr.Reviews) != len(wantStates) {
+		t.Fatalf("reviews = %+v, want %v", pr.Reviews, wantStates)
+	}
+	for _, review := range pr.Reviews {
+		if wantStates[review.User] != review.State {
+			t.Errorf("review of %s = %s, want %s", review.User, review.State, wantStates[review.User])
+		}
+	}
+	if len(pr.RequestedReviewers) != 1 || pr.RequestedReviewers[0] != "carol" {
+		t.Errorf("requested reviewers = %v, want [carol]", pr.RequestedReviewers)
+	}
+	if pr.Additions != 3 || pr.Deletions != 1 || pr.ChangedFiles != 2 {
+		t.Errorf("diff stats = +%d -%d in %d files, want +3 -1 in 2 files", pr.Additions, pr.Deletions, pr.ChangedFiles)
+	}
+
+	if !prs[1].Draft {
+		t.Error("PR #8 should be a draft")
+	}
+}
@@ -1,48 +1,319 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	GitHub GitHubConfig `yaml:"github"`
-	Email  EmailConfig  `yaml:"email"`
-	Rules  RulesConfig  `yaml:"rules"`
-	Debug  DebugConfig  `yaml:"debug"`
+	GitHub   GitHubConfig   `yaml:"github"`
+	Email    EmailConfig    `yaml:"email"`
+	Rules    RulesConfig    `yaml:"rules"`
+	Debug    DebugConfig    `yaml:"debug"`
+	Health   HealthConfig   `yaml:"health"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	Reload   ReloadConfig   `yaml:"reload"`
+	Shutdown ShutdownConfig `yaml:"shutdown"`
+	Webhook  WebhookConfig  `yaml:"webhook"`
+	State    StateConfig    `yaml:"state"`
+	Sources  []SourceConfig `yaml:"sources"`
+	Actions  ActionsConfig  `yaml:"actions"`
+
+	source       string            // Config file the values were loaded from, if any
+	lines        map[string]int    // YAML path to line number, for validation messages
+	envOverrides map[string]string // YAML path to the PRW_ variable that overrode it
 }
 
 type GitHubConfig struct {
 	Token     string   `yaml:"token"`
+	TokenFile string   `yaml:"token_file,omitempty"` // Read the token from this file instead
 	Owner     string   `yaml:"owner"`
 	Repos     []string `yaml:"repos"`
 	BaseURL   string   `yaml:"base_url,omitempty"`
 	UploadURL string   `yaml:"upload_url,omitempty"`
+
+	Discovery DiscoveryConfig `yaml:"discovery"`
+
+	// API selects how pull requests are fetched: "auto" (GraphQL, falling
+	// back to REST on failure), "graphql" or "rest"
+	API string `yaml:"api"`
+
+	// Timeout bounds each GitHub API call (default: 30s)
+	Timeout time.Duration `yaml:"timeout"`
+
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+
+	// Concurrency is the number of repositories, or GraphQL batches of
+	// repositories, fetched in parallel (default: 4)
+	Concurrency int `yaml:"concurrency"`
+
+	// App authenticates as a GitHub App instead of with github.token
+	App AppConfig `yaml:"app"`
+
+	// Checks fetches the CI status of the head commit of every PR
+	Checks ChecksConfig `yaml:"checks"`
+
+	// Owners lists additional organizations or users, each with its own
+	// repositories, discovery settings and optionally its own token
+	Owners []OwnerConfig `yaml:"owners"`
+}
+
+// AppConfig identifies a GitHub App. Owners without their own token are
+// accessed through the app's installation on them.
+type AppConfig struct {
+	ID             int64  `yaml:"id"`
+	PrivateKey     string `yaml:"private_key,omitempty"`      // PEM encoded
+	PrivateKeyFile string `yaml:"private_key_file,omitempty"` // Read the private key from this file instead
+	DiscoverRepos  bool   `yaml:"discover_repos"`             // Check every repository the installations can access
+}
+
+// RateLimitConfig controls how the GitHub client paces itself against the
+// API rate limits and retries transient failures
+type RateLimitConfig struct {
+	MinRemaining int           `yaml:"min_remaining"` // Wait for the reset when fewer requests remain (default: 50)
+	MaxWait      time.Duration `yaml:"max_wait"`      // Longest wait for a reset before failing instead (default: 15m)
+	MaxRetries   int           `yaml:"max_retries"`   // Retries for secondary rate limits and 5xx responses of reads (default: 3)
+	RetryBackoff time.Duration `yaml:"retry_backoff"` // First retry delay, doubled on each attempt with jitter (default: 2s)
+	CacheSize    int           `yaml:"cache_size"`    // Responses kept for ETag revalidation (default: 1000, -1 disables)
+}
+
+type OwnerConfig struct {
+	Name      string          `yaml:"name"`
+	Token     string          `yaml:"token,omitempty"`      // Defaults to github.token
+	TokenFile string          `yaml:"token_file,omitempty"` // Read the token from this file instead
+	Repos     []string        `yaml:"repos"`
+	Discovery DiscoveryConfig `yaml:"discovery"`
+}
+
+// OwnerTarget is an owner together with the token and repositories used to
+// check it, after merging github.owner/repos with github.owners
+type OwnerTarget struct {
+	Owner     string
+	Token     string
+	Repos     []string
+	Discovery DiscoveryConfig
+}
+
+// Targets groups the configured repositories by owner. Entries in
+// github.repos may be "owner/repo"; bare names belong to github.owner.
+// Owners without their own token use github.token.
+func (g GitHubConfig) Targets() []OwnerTarget {
+	var targets []*OwnerTarget
+	index := make(map[string]*OwnerTarget)
+	target := func(owner string) *OwnerTarget {
+		key := strings.ToLower(owner)
+		if t, ok := index[key]; ok {
+			return t
+		}
+		t := &OwnerTarget{Owner: owner, Token: g.Token}
+		index[key] = t
+		targets = append(targets, t)
+		return t
+	}
+
+	if g.Owner != "" {
+		target(g.Owner).Discovery = g.Discovery
+	}
+	for _, repo := range g.Repos {
+		owner, name := SplitRepo(g.Owner, repo)
+		t := target(owner)
+		t.Repos = append(t.Repos, name)
+	}
+	for _, o := range g.Owners {
+		t := target(o.Name)
+		if o.Token != "" {
+			t.Token = o.Token
+		}
+		t.Repos = append(t.Repos, o.Repos...)
+		if o.Discovery.Enabled {
+			t.Discovery = o.Discovery
+		}
+	}
+
+	result := make([]OwnerTarget, 0, len(targets))
+	for _, t := range targets {
+		result = append(result, *t)
+	}
+	return result
+}
+
+// Configured reports whether any GitHub setting is present. Without one,
+// GitHub is not checked and only the configured sources are.
+func (g GitHubConfig) Configured() bool {
+	return g.Token != "" || g.Owner != "" || len(g.Repos) > 0 || len(g.Owners) > 0 || g.App.ID != 0
+}
+
+// SourceConfig is a code host other than GitHub whose open pull or merge
+// requests are checked with the same rules
+type SourceConfig struct {
+	Name      string          `yaml:"name"`                 // Identifies the source in logs and notifications
+	Type      string          `yaml:"type"`                 // gitlab, gitea, forgejo or bitbucket (Server/Data Center)
+	BaseURL   string          `yaml:"base_url"`             // Web URL of the instance (default for gitlab: https://gitlab.com)
+	Token     string          `yaml:"token"`                // Access token with read access to the API
+	TokenFile string          `yaml:"token_file,omitempty"` // Read the token from this file instead
+	Owner     string          `yaml:"owner"`                // Group, organization, project key or user the bare names in repos and discovery belong to
+	Repos     []string        `yaml:"repos"`                // Repository names of owner, or full paths such as owner/repo or group/subgroup/project
+	Discovery DiscoveryConfig `yaml:"discovery"`
+	Timeout   time.Duration   `yaml:"timeout"` // Bounds each API call (default: 30s)
+}
+
+// Targets groups the repositories of a source by owner. Full paths are
+// split at the last slash, so "group/subgroup/project" belongs to
+// "group/subgroup"; bare names belong to owner.
+func (s SourceConfig) Targets() []OwnerTarget {
+	var targets []*OwnerTarget
+	index := make(map[string]*OwnerTarget)
+	target := func(owner string) *OwnerTarget {
+		key := strings.ToLower(owner)
+		if t, ok := index[key]; ok {
+			return t
+		}
+		t := &OwnerTarget{Owner: owner, Token: s.Token}
+		index[key] = t
+		targets = append(targets, t)
+		return t
+	}
+
+	if s.Owner != "" {
+		target(s.Owner).Discovery = s.Discovery
+	}
+	for _, repo := range s.Repos {
+		owner, name := s.Owner, repo
+		if i := strings.LastIndex(repo, "/"); i >= 0 {
+			owner, name = repo[:i], repo[i+1:]
+		}
+		t := target(owner)
+		t.Repos = append(t.Repos, name)
+	}
+
+	result := make([]OwnerTarget, 0, len(targets))
+	for _, t := range targets {
+		result = append(result, *t)
+	}
+	return result
+}
+
+// SplitRepo splits an "owner/repo" reference, using defaultOwner for bare
+// repository names
+func SplitRepo(defaultOwner, repo string) (owner, name string) {
+	if o, n, ok := strings.Cut(repo, "/"); ok {
+		return o, n
+	}
+	return defaultOwner, repo
+}
+
+// DiscoveryConfig enables monitoring every repository of the owner that
+// matches the filters, in addition to those listed in repos
+type DiscoveryConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Include         []string      `yaml:"include"`          // Glob patterns, or regular expressions wrapped in slashes
+	Exclude         []string      `yaml:"exclude"`          // Glob patterns, or regular expressions wrapped in slashes
+	Topics          []string      `yaml:"topics"`           // Only repositories with at least one of these topics
+	Visibility      string        `yaml:"visibility"`       // all, public, private or internal
+	IncludeArchived bool          `yaml:"include_archived"` // Archived repositories are skipped by default
+	IncludeForks    bool          `yaml:"include_forks"`    // Forks are skipped by default
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // How often to re-list repositories in watch mode
 }
 
 type EmailConfig struct {
-	SMTPHost     string        `yaml:"smtp_host"`
-	SMTPPort     int           `yaml:"smtp_port"`
-	SMTPUsername string        `yaml:"smtp_username"`
-	SMTPPassword string        `yaml:"smtp_password"`
-	From         string        `yaml:"from"`
-	To           []string      `yaml:"to"`
-	Subject      string        `yaml:"subject"`
-	RateLimit    time.Duration `yaml:"rate_limit"`   // Rate limit between emails
-	RateTimeout  time.Duration `yaml:"rate_timeout"` // Timeout for rate limiting
+	SMTPHost         string        `yaml:"smtp_host"`
+	SMTPPort         int           `yaml:"smtp_port"`
+	SMTPUsername     string        `yaml:"smtp_username"`
+	SMTPPassword     string        `yaml:"smtp_password"`
+	SMTPPasswordFile string        `yaml:"smtp_password_file,omitempty"` // Read the SMTP password from this file instead
+	From             string        `yaml:"from"`
+	To               []string      `yaml:"to"`
+	Subject          string        `yaml:"subject"`
+	RateLimit        time.Duration `yaml:"rate_limit"`   // Rate limit between emails
+	RateTimeout      time.Duration `yaml:"rate_timeout"` // Timeout for rate limiting
+	Timeout          time.Duration `yaml:"timeout"`      // Timeout for each attempt to deliver an email (default: 1m)
+
+	// Users maps logins to email addresses, for notifications sent to the
+	// people a pull request is waiting on. Authors whose address the forge
+	// returns need no entry. Notifications go to "to" when nobody is found.
+	Users map[string]string `yaml:"users"`
+
+	// NotifyWaitingOn sends approval reminders, merge reminders and draft
+	// notifications to whoever a pull request is waiting on, looked up like
+	// for inactivity reminders, instead of to "to"
+	NotifyWaitingOn bool `yaml:"notify_waiting_on"`
+}
+
+// ChecksConfig selects whether the commit statuses and check runs of pull
+// requests are fetched, and which of them have to pass
+type ChecksConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Required names the checks that have to pass before a PR is ready to
+	// merge; empty requires all of them
+	Required []string `yaml:"required"`
+
+	// Repos replaces Required for some owners or repositories, keyed by
+	// owner or owner/repo
+	Repos map[string][]string `yaml:"repos"`
+
+	// MissingTimeout is how long after a push a required check that has
+	// not reported keeps a PR pending; after that it is ignored
+	MissingTimeout time.Duration `yaml:"missing_timeout"`
+}
+
+// RequiredFor returns the required checks of a repository: those listed
+// for the repository in Repos, else those listed for its owner, else
+// Required. Keys are matched ignoring case.
+func (c ChecksConfig) RequiredFor(owner, repo string) []string {
+	var ownerChecks []string
+	ownerFound := false
+	for key, checks := range c.Repos {
+		switch {
+		case strings.EqualFold(key, owner+"/"+repo):
+			return checks
+		case strings.EqualFold(key, owner):
+			ownerChecks, ownerFound = checks, true
+		}
+	}
+	if ownerFound {
+		return ownerChecks
+	}
+	return c.Required
 }
 
 type RulesConfig struct {
-	ApprovalTime      time.Duration `yaml:"approval_time"`
-	MergeReminderTime time.Duration `yaml:"merge_reminder_time"`
-	MergeTime         time.Duration `yaml:"merge_time"`
-	DraftTime         time.Duration `yaml:"draft_time"`
-	CheckInterval     time.Duration `yaml:"check_interval"`
-	EscalationEmail   string        `yaml:"escalation_email"`
-	PRSize            PRSizeConfig  `yaml:"pr_size"`
+	ApprovalTime      time.Duration   `yaml:"approval_time"`
+	MergeReminderTime time.Duration   `yaml:"merge_reminder_time"`
+	MergeTime         time.Duration   `yaml:"merge_time"`
+	DraftTime         time.Duration   `yaml:"draft_time"`
+	CheckInterval     time.Duration   `yaml:"check_interval"`
+	Schedule          string          `yaml:"schedule"`     // Cron expression, overrides check_interval
+	Timezone          string          `yaml:"timezone"`     // IANA zone for schedule and active hours
+	ActiveHours       string          `yaml:"active_hours"` // Only run between "HH:MM-HH:MM"
+	ActiveDays        []string        `yaml:"active_days"`  // Only run on these weekdays, e.g. ["Mon-Fri"]
+	Jitter            time.Duration   `yaml:"jitter"`       // Random delay added to each scheduled run
+	EscalationEmail   string          `yaml:"escalation_email"`
+	PRSize            PRSizeConfig    `yaml:"pr_size"`
+	Inactivity        InactivityRules `yaml:"inactivity"`
+}
+
+// InactivityRules remind whoever a pull request is waiting on, the reviewers
+// or its author, once it has waited on them this long, instead of reminding
+// by age. When either is set they replace the approval reminder, so PRs
+// that are being actively iterated on are left alone; zero disables a side.
+type InactivityRules struct {
+	WaitingOnReviewer time.Duration `yaml:"waiting_on_reviewer"`
+	WaitingOnAuthor   time.Duration `yaml:"waiting_on_author"`
+}
+
+// Enabled reports whether inactivity replaces age for approval reminders
+func (r InactivityRules) Enabled() bool {
+	return r.WaitingOnReviewer > 0 || r.WaitingOnAuthor > 0
 }
 
 type PRSizeConfig struct {
@@ -80,37 +351,226 @@ type DebugConfig struct {
 	Concurrency int  `yaml:"concurrency"`
 }
 
+type ShutdownConfig struct {
+	GracePeriod time.Duration `yaml:"grace_period"` // How long to wait for a canceled run to stop on shutdown (default: 30s)
+}
+
+// WebhookConfig enables the webhook receiver. Pull request events keep a
+// cache of open PRs current, so scheduled runs only call the GitHub API for
+// repositories not cached yet and for the periodic full reconciliation.
+type WebhookConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	Listen            string        `yaml:"listen"`                // Address to receive webhooks on; may be shared with health.listen (default: ":8080")
+	Path              string        `yaml:"path"`                  // URL path of the endpoint (default: /webhook)
+	Secret            string        `yaml:"secret"`                // Webhook secret used to verify X-Hub-Signature-256
+	SecretFile        string        `yaml:"secret_file,omitempty"` // Read the secret from this file instead
+	ReconcileInterval time.Duration `yaml:"reconcile_interval"`    // How often the cache is rebuilt from the API (default: 6h)
+}
+
+// ActionsConfig enables changes the watcher makes on the pull requests
+// themselves, in addition to sending emails
+type ActionsConfig struct {
+	Comments  CommentsConfig  `yaml:"comments"`
+	Labels    LabelsConfig    `yaml:"labels"`
+	Reviewers ReviewersConfig `yaml:"reviewers"`
+	Drafts    DraftsConfig    `yaml:"drafts"`
+	Snooze    SnoozeConfig    `yaml:"snooze"`
+}
+
+// CommentsConfig keeps a single reminder comment on pull requests that are
+// due for a notification, edited in place on later runs instead of adding a
+// new comment each time
+type CommentsConfig struct {
+	Enabled            bool     `yaml:"enabled"`
+	Events             []string `yaml:"events"`              // approval_reminder, merge_reminder, escalation, waiting_on_reviewer, waiting_on_author, ci_failing (default: all)
+	Reviewers          []string `yaml:"reviewers"`           // Mentioned for approval when a PR has no requested reviewers
+	EscalationMentions []string `yaml:"escalation_mentions"` // Also mentioned on escalations, e.g. a team lead or "org/team"
+}
+
+// CommentEvents are the notification types a reminder comment can be kept for
+var CommentEvents = []string{"approval_reminder", "merge_reminder", "escalation", "waiting_on_reviewer", "waiting_on_author", "ci_failing"}
+
+// Commented reports whether reminder comments are kept for event
+func (c CommentsConfig) Commented(event string) bool {
+	return c.Enabled && slices.Contains(c.Events, event)
+}
+
+// LabelsConfig maintains labels on each pull request that show its SLA
+// status and size. Labels are created with the configured colors when the
+// repository does not have them yet, and removed when they no longer apply.
+type LabelsConfig struct {
+	Enabled     bool        `yaml:"enabled"`
+	NeedsReview LabelConfig `yaml:"needs_review"` // Past approval_time without enough reviews (default: sla:needs-review)
+	Overdue     LabelConfig `yaml:"overdue"`      // Approved but past merge_reminder_time, or a draft past draft_time (default: sla:overdue)
+	Escalated   LabelConfig `yaml:"escalated"`    // Past merge_time (default: sla:escalated)
+	Size        LabelConfig `yaml:"size"`         // Name is a prefix for the size category, e.g. size:M (default: size:)
+}
+
+// LabelConfig is the name and color of a label managed by the watcher
+type LabelConfig struct {
+	Name  string `yaml:"name"`
+	Color string `yaml:"color"` // Six hex digits, e.g. "d93f0b"
+}
+
+// ReviewersConfig requests reviewers for pull requests that pass
+// approval_time without any requested reviewer. It only applies to the
+// repositories listed in repos.
+type ReviewersConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Repos    []string `yaml:"repos"`    // owner/repo names or glob patterns such as "acme/*"; bare names belong to github.owner
+	Strategy string   `yaml:"strategy"` // round_robin, least_loaded or codeowners (default: round_robin)
+	Team     []string `yaml:"team"`     // Logins to choose from with round_robin and least_loaded
+	Count    int      `yaml:"count"`    // Reviewers requested per pull request (default: 1)
+}
+
+// Applies reports whether reviewers are requested in the repository with
+// the given full name
+func (r ReviewersConfig) Applies(defaultOwner, fullName string) bool {
+	if !r.Enabled {
+		return false
+	}
+	for _, repo := range r.Repos {
+		if !strings.Contains(repo, "/") {
+			repo = defaultOwner + "/" + repo
+		}
+		if ok, _ := path.Match(strings.ToLower(repo), strings.ToLower(fullName)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DraftsConfig is the lifecycle of drafts abandoned by their authors. They
+// are emailed about at draft_time as before; after warn_after without a push
+// or comment they get a warning comment and label, and after close_after
+// they are closed, if close is set.
+type DraftsConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	WarnAfter   time.Duration `yaml:"warn_after"`   // Inactivity before the warning (default: 336h)
+	CloseAfter  time.Duration `yaml:"close_after"`  // Inactivity before closing (default: 720h)
+	Close       bool          `yaml:"close"`        // Close drafts at close_after instead of leaving them labeled
+	Label       LabelConfig   `yaml:"label"`        // Added with the warning (default: stale-draft)
+	ExemptLabel string        `yaml:"exempt_label"` // Drafts with this label are left alone (default: keep-open)
+}
+
+// SnoozeConfig lets people who are already on a pull request silence its
+// reminders, with a "/pr-watcher snooze 2d" comment or a signed link in the
+// email. A snooze ends when it expires or the pull request gets a new push
+// or review.
+type SnoozeConfig struct {
+	Enabled bool              `yaml:"enabled"`
+	Default time.Duration     `yaml:"default"` // For "snooze" without a duration and "ack" (default: 24h)
+	Max     time.Duration     `yaml:"max"`     // Longest snooze allowed (default: 168h)
+	Users   []string          `yaml:"users"`   // Logins allowed to use comment commands; empty allows anyone who can comment
+	Links   SnoozeLinksConfig `yaml:"links"`
+}
+
+// SnoozeLinksConfig adds signed snooze links to emails. The links are served
+// by the watcher's HTTP server in watch mode.
+type SnoozeLinksConfig struct {
+	Enabled    bool            `yaml:"enabled"`
+	BaseURL    string          `yaml:"base_url"`              // Public URL of the watcher's HTTP server, e.g. https://pr-watcher.example.com
+	Listen     string          `yaml:"listen"`                // Address to serve the links on; may be shared with health.listen and webhook.listen (default: ":8080")
+	Path       string          `yaml:"path"`                  // URL path of the links (default: /snooze)
+	Secret     string          `yaml:"secret"`                // Key the links are signed with
+	SecretFile string          `yaml:"secret_file,omitempty"` // Read the secret from this file instead
+	Durations  []time.Duration `yaml:"durations"`             // Snoozes offered in emails (default: 24h and 72h)
+	ValidFor   time.Duration   `yaml:"valid_for"`             // How long links work after the email is sent (default: 168h)
+}
+
+// StateConfig sets where state that outlives a run is kept
+type StateConfig struct {
+	File string `yaml:"file"` // JSON file for persisted state, empty keeps it in memory
+}
+
+type HealthConfig struct {
+	Listen                 string        `yaml:"listen"`                   // Address for /healthz and /readyz, empty disables the server
+	FailureThreshold       int           `yaml:"failure_threshold"`        // Notification failures tolerated per run before it is reported as failed; repositories that cannot be fetched do not count
+	MaxConsecutiveFailures int           `yaml:"max_consecutive_failures"` // Failed runs in a row before /healthz reports unhealthy
+	MaxRunDuration         time.Duration `yaml:"max_run_duration"`         // Runs taking longer are considered stuck
+	ProbeTimeout           time.Duration `yaml:"probe_timeout"`            // Timeout for GitHub and SMTP reachability probes
+}
+
+type LoggingConfig struct {
+	Level      string `yaml:"level"`       // error, info, debug or verbose; debug flags raise it
+	Format     string `yaml:"format"`      // text or json
+	Output     string `yaml:"output"`      // stderr, stdout or file
+	File       string `yaml:"file"`        // Log file path when output is file
+	MaxSizeMB  int    `yaml:"max_size_mb"` // Rotate the log file after this many megabytes
+	MaxBackups int    `yaml:"max_backups"` // Rotated log files to keep
+}
+
+type ReloadConfig struct {
+	WatchFile    bool          `yaml:"watch_file"`    // Reload when the config file changes, in addition to SIGHUP
+	PollInterval time.Duration `yaml:"poll_interval"` // How often to check the config file for changes
+}
+
+// Load builds the configuration in layers: the YAML file (with ${VAR}
+// references expanded) or, if it does not exist, the legacy environment
+// variables; then PRW_ environment overrides; then secrets read from
+// *_file paths; and finally defaults for anything left unset.
 func Load(filename string) (*Config, error) {
+	var config *Config
 	if _, err := os.Stat(filename); err == nil {
 		data, err := os.ReadFile(filename)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 
-		var config Config
-		if err := yaml.Unmarshal(data, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		config, err = parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", filename, err)
+		}
+		config.source = filename
+	} else {
+		config, err = loadLegacyEnv()
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	if err := applyEnvOverrides(config); err != nil {
+		return nil, err
+	}
+	if err := resolveSecretFiles(config); err != nil {
+		return nil, err
+	}
+
+	setDefaults(config)
+	return config, nil
+}
 
-		setDefaults(&config)
-		return &config, nil
+// loadLegacyEnv reads the unprefixed environment variables supported before
+// PRW_ overrides existed. They are only consulted when there is no config file.
+func loadLegacyEnv() (*Config, error) {
+	var envErrors []string
+	envDuration := func(name string, fallback time.Duration) time.Duration {
+		d, err := parseDuration(os.Getenv(name), fallback)
+		if err != nil {
+			envErrors = append(envErrors, fmt.Sprintf("%s: %v", name, err))
+		}
+		return d
 	}
 
 	config := &Config{
 		GitHub: GitHubConfig{
 			Token: os.Getenv("GITHUB_TOKEN"),
 			Owner: os.Getenv("GITHUB_OWNER"),
+			Repos: splitList(os.Getenv("GITHUB_REPOS")),
 		},
 		Email: EmailConfig{
 			SMTPHost:     os.Getenv("SMTP_HOST"),
 			SMTPUsername: os.Getenv("SMTP_USERNAME"),
 			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 			From:         os.Getenv("EMAIL_FROM"),
+			To:           splitList(os.Getenv("EMAIL_TO")),
 		},
 		Rules: RulesConfig{
-			ApprovalTime:  parseDuration(os.Getenv("APPROVAL_TIME"), 2*time.Hour),
-			MergeTime:     parseDuration(os.Getenv("MERGE_TIME"), 4*time.Hour),
-			CheckInterval: parseDuration(os.Getenv("CHECK_INTERVAL"), 30*time.Minute),
+			ApprovalTime:  envDuration("APPROVAL_TIME", 2*time.Hour),
+			MergeTime:     envDuration("MERGE_TIME", 4*time.Hour),
+			CheckInterval: envDuration("CHECK_INTERVAL", 30*time.Minute),
+			Schedule:      os.Getenv("SCHEDULE"),
+			Timezone:      os.Getenv("TIMEZONE"),
 		},
 		Debug: DebugConfig{
 			Enabled:     os.Getenv("DEBUG") == "true",
@@ -118,20 +578,110 @@ func Load(filename string) (*Config, error) {
 			SkipEmails:  os.Getenv("SKIP_EMAILS") == "true",
 			Concurrency: 5,
 		},
+		Health: HealthConfig{
+			Listen: os.Getenv("HEALTH_LISTEN"),
+		},
+		Logging: LoggingConfig{
+			Level:  os.Getenv("LOG_LEVEL"),
+			Format: os.Getenv("LOG_FORMAT"),
+			Output: os.Getenv("LOG_OUTPUT"),
+			File:   os.Getenv("LOG_FILE"),
+		},
+	}
+
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			envErrors = append(envErrors, fmt.Sprintf("SMTP_PORT: %v", err))
+		}
+		config.Email.SMTPPort = n
 	}
 
-	if repos := os.Getenv("GITHUB_REPOS"); repos != "" {
-		config.GitHub.Repos = []string{repos}
+	if len(envErrors) > 0 {
+		return nil, fmt.Errorf("invalid environment configuration: %s", strings.Join(envErrors, "; "))
 	}
+	return config, nil
+}
 
-	if to := os.Getenv("EMAIL_TO"); to != "" {
-		config.Email.To = []string{to}
+// parse decodes a YAML document strictly, with ${VAR} references expanded:
+// unknown keys and values of the wrong type are errors, each reported with
+// its line number
+func parse(data []byte) (*Config, error) {
+	config := &Config{lines: make(map[string]int)}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return config, nil
 	}
 
-	setDefaults(config)
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if err := interpolate(&root); err != nil {
+		return nil, err
+	}
+	recordLines(&root, "", config.lines)
+
+	var unknown []string
+	checkKnownFields(&root, reflect.TypeOf(config), &unknown)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("yaml: unmarshal errors:\n  %s", strings.Join(unknown, "\n  "))
+	}
+	if err := root.Decode(config); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
+// checkKnownFields reports the mapping keys that name no field of the struct
+// they would be decoded into, like a yaml.Decoder with KnownFields, which
+// decoding a yaml.Node does not support
+func checkKnownFields(node *yaml.Node, t reflect.Type, problems *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			checkKnownFields(child, t, problems)
+		}
+	case yaml.AliasNode:
+		checkKnownFields(node.Alias, t, problems)
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, item := range node.Content {
+				checkKnownFields(item, t.Elem(), problems)
+			}
+		}
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Map:
+			for i := 1; i < len(node.Content); i += 2 {
+				checkKnownFields(node.Content[i], t.Elem(), problems)
+			}
+		case reflect.Struct:
+			fields := make(map[string]reflect.Type, t.NumField())
+			for i := 0; i < t.NumField(); i++ {
+				if field := t.Field(i); field.IsExported() && yamlName(field) != "-" {
+					fields[yamlName(field)] = field.Type
+				}
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if key.Value == "<<" {
+					checkKnownFields(value, t, problems)
+					continue
+				}
+				fieldType, ok := fields[key.Value]
+				if !ok {
+					*problems = append(*problems, fmt.Sprintf("line %d: field %s not found in type %s", key.Line, key.Value, t))
+					continue
+				}
+				checkKnownFields(value, fieldType, problems)
+			}
+		}
+	}
+}
+
 func setDefaults(config *Config) {
 	if config.Rules.ApprovalTime == 0 {
 		config.Rules.ApprovalTime = 2 * time.Hour
@@ -160,6 +710,148 @@ func setDefaults(config *Config) {
 	if config.Debug.Concurrency == 0 {
 		config.Debug.Concurrency = 5
 	}
+	if config.Health.MaxConsecutiveFailures == 0 {
+		config.Health.MaxConsecutiveFailures = 3
+	}
+	if config.Health.MaxRunDuration == 0 {
+		config.Health.MaxRunDuration = 30 * time.Minute
+	}
+	if config.Health.ProbeTimeout == 0 {
+		config.Health.ProbeTimeout = 5 * time.Second
+	}
+	if config.GitHub.API == "" {
+		config.GitHub.API = "auto"
+	}
+	if config.GitHub.Timeout == 0 {
+		config.GitHub.Timeout = 30 * time.Second
+	}
+	if config.Email.Timeout == 0 {
+		config.Email.Timeout = time.Minute
+	}
+	if config.Shutdown.GracePeriod == 0 {
+		config.Shutdown.GracePeriod = 30 * time.Second
+	}
+	if config.GitHub.Concurrency == 0 {
+		config.GitHub.Concurrency = 4
+	}
+	if config.Webhook.Listen == "" {
+		config.Webhook.Listen = ":8080"
+	}
+	if config.Webhook.Path == "" {
+		config.Webhook.Path = "/webhook"
+	}
+	if config.Webhook.ReconcileInterval == 0 {
+		config.Webhook.ReconcileInterval = 6 * time.Hour
+	}
+	if config.GitHub.RateLimit.MinRemaining == 0 {
+		config.GitHub.RateLimit.MinRemaining = 50
+	}
+	if config.GitHub.RateLimit.MaxWait == 0 {
+		config.GitHub.RateLimit.MaxWait = 15 * time.Minute
+	}
+	if config.GitHub.RateLimit.MaxRetries == 0 {
+		config.GitHub.RateLimit.MaxRetries = 3
+	}
+	if config.GitHub.RateLimit.RetryBackoff == 0 {
+		config.GitHub.RateLimit.RetryBackoff = 2 * time.Second
+	}
+	if config.GitHub.RateLimit.CacheSize == 0 {
+		config.GitHub.RateLimit.CacheSize = 1000
+	}
+	labels := &config.Actions.Labels
+	for _, d := range []struct {
+		label       *LabelConfig
+		name, color string
+	}{
+		{&labels.NeedsReview, "sla:needs-review", "fbca04"},
+		{&labels.Overdue, "sla:overdue", "d93f0b"},
+		{&labels.Escalated, "sla:escalated", "b60205"},
+		{&labels.Size, "size:", "ededed"},
+		{&config.Actions.Drafts.Label, "stale-draft", "cfd3d7"},
+	} {
+		if d.label.Name == "" {
+			d.label.Name = d.name
+		}
+		if d.label.Color == "" {
+			d.label.Color = d.color
+		}
+		d.label.Color = strings.TrimPrefix(d.label.Color, "#")
+	}
+	if config.Actions.Drafts.WarnAfter == 0 {
+		config.Actions.Drafts.WarnAfter = 14 * 24 * time.Hour
+	}
+	if config.Actions.Drafts.CloseAfter == 0 {
+		config.Actions.Drafts.CloseAfter = 30 * 24 * time.Hour
+	}
+	if config.Actions.Drafts.ExemptLabel == "" {
+		config.Actions.Drafts.ExemptLabel = "keep-open"
+	}
+	snooze := &config.Actions.Snooze
+	if snooze.Default == 0 {
+		snooze.Default = 24 * time.Hour
+	}
+	if snooze.Max == 0 {
+		snooze.Max = 7 * 24 * time.Hour
+	}
+	if snooze.Links.Listen == "" {
+		snooze.Links.Listen = ":8080"
+	}
+	if snooze.Links.Path == "" {
+		snooze.Links.Path = "/snooze"
+	}
+	if len(snooze.Links.Durations) == 0 {
+		snooze.Links.Durations = []time.Duration{24 * time.Hour, 72 * time.Hour}
+	}
+	if snooze.Links.ValidFor == 0 {
+		snooze.Links.ValidFor = 7 * 24 * time.Hour
+	}
+	if config.Actions.Reviewers.Strategy == "" {
+		config.Actions.Reviewers.Strategy = "round_robin"
+	}
+	if config.Actions.Reviewers.Count == 0 {
+		config.Actions.Reviewers.Count = 1
+	}
+	if len(config.Actions.Comments.Events) == 0 {
+		config.Actions.Comments.Events = slices.Clone(CommentEvents)
+	}
+	if config.GitHub.Discovery.RefreshInterval == 0 {
+		config.GitHub.Discovery.RefreshInterval = 6 * time.Hour
+	}
+	if config.GitHub.Checks.MissingTimeout == 0 {
+		config.GitHub.Checks.MissingTimeout = time.Hour
+	}
+	for i := range config.Sources {
+		source := &config.Sources[i]
+		if source.Type == "gitlab" && source.BaseURL == "" {
+			source.BaseURL = "https://gitlab.com"
+		}
+		if source.Timeout == 0 {
+			source.Timeout = 30 * time.Second
+		}
+		if source.Discovery.RefreshInterval == 0 {
+			source.Discovery.RefreshInterval = config.GitHub.Discovery.RefreshInterval
+		}
+	}
+	for i := range config.GitHub.Owners {
+		if config.GitHub.Owners[i].Discovery.RefreshInterval == 0 {
+			config.GitHub.Owners[i].Discovery.RefreshInterval = config.GitHub.Discovery.RefreshInterval
+		}
+	}
+	if config.Reload.PollInterval == 0 {
+		config.Reload.PollInterval = 10 * time.Second
+	}
+	if config.Logging.Format == "" {
+		config.Logging.Format = "text"
+	}
+	if config.Logging.Output == "" {
+		config.Logging.Output = "stderr"
+	}
+	if config.Logging.MaxSizeMB == 0 {
+		config.Logging.MaxSizeMB = 100
+	}
+	if config.Logging.MaxBackups == 0 {
+		config.Logging.MaxBackups = 5
+	}
 	setPRSizeDefaults(&config.Rules.PRSize, &config.Rules)
 }
 
@@ -204,14 +896,14 @@ func setPRSizeDefaults(prSize *PRSizeConfig, rules *RulesConfig) {
 	}
 }
 
-// parseDuration parses duration from string with fallback
-func parseDuration(s string, fallback time.Duration) time.Duration {
+// parseDuration parses duration from string, using fallback when it is empty
+func parseDuration(s string, fallback time.Duration) (time.Duration, error) {
 	if s == "" {
-		return fallback
+		return fallback, nil
 	}
 	d, err := time.ParseDuration(s)
 	if err != nil {
-		return fallback
+		return fallback, err
 	}
-	return d
+	return d, nil
 }
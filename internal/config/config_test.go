@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_UnknownField(t *testing.T) {
+	path := writeConfig(t, `
+github:
+  token: "t"
+  owner: "o"
+  repo: ["r"]
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Expected error for unknown field")
+	}
+	if !strings.Contains(err.Error(), "line 5") || !strings.Contains(err.Error(), "repo") {
+		t.Errorf("Expected error to name the field and line, got: %v", err)
+	}
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	path := writeConfig(t, `
+github:
+  owner: "o"
+  repos: ["r"]
+email:
+  smtp_host: "smtp.example.com"
+  smtp_port: 587
+  from: "not an address"
+  to: ["team@example.com"]
+rules:
+  approval_time: "80h"
+  merge_time: "72h"
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	err = cfg.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	expected := map[string]int{
+		"github.token":        2,
+		"email.from":          8,
+		"rules.approval_time": 11,
+	}
+	if len(verr.Problems) != len(expected) {
+		t.Errorf("Expected %d problems, got %d: %v", len(expected), len(verr.Problems), err)
+	}
+	for _, p := range verr.Problems {
+		line, ok := expected[p.Path]
+		if !ok {
+			t.Errorf("Unexpected problem: %s: %s", p.Path, p.Message)
+			continue
+		}
+		if p.Line != line {
+			t.Errorf("Expected %s on line %d, got %d", p.Path, line, p.Line)
+		}
+	}
+}
+
+func TestValidate_ExampleConfig(t *testing.T) {
+	cfg, err := Load("../../config.yaml.example")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Example config should be valid: %v", err)
+	}
+}
+
+func TestLoad_Layered(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("ghp_from_file\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	t.Setenv("TEAM_OWNER", "acme")
+	t.Setenv("PRW_EMAIL_TO", "a@example.com, b@example.com")
+	t.Setenv("PRW_RULES_APPROVAL_TIME", "3h")
+	t.Setenv("PRW_GITHUB_TOKEN_FILE", tokenFile)
+
+	path := writeConfig(t, `
+github:
+  token: "ignored"
+  owner: "${TEAM_OWNER}"
+  repos: ["${TEAM_REPO:-api}"]
+email:
+  to: ["file@example.com"]
+rules:
+  approval_time: "1h"
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.GitHub.Owner != "acme" {
+		t.Errorf("Expected interpolated owner 'acme', got %q", cfg.GitHub.Owner)
+	}
+	if len(cfg.GitHub.Repos) != 1 || cfg.GitHub.Repos[0] != "api" {
+		t.Errorf("Expected default repo 'api', got %v", cfg.GitHub.Repos)
+	}
+	if cfg.GitHub.Token != "ghp_from_file" {
+		t.Errorf("Expected token from token_file, got %q", cfg.GitHub.Token)
+	}
+	if len(cfg.Email.To) != 2 || cfg.Email.To[1] != "b@example.com" {
+		t.Errorf("Expected recipients from PRW_EMAIL_TO, got %v", cfg.Email.To)
+	}
+	if cfg.Rules.ApprovalTime.String() != "3h0m0s" {
+		t.Errorf("Expected approval time override 3h, got %v", cfg.Rules.ApprovalTime)
+	}
+}
+
+func TestLoad_UndefinedVariable(t *testing.T) {
+	path := writeConfig(t, `
+github:
+  token: "${PRW_TEST_UNDEFINED_TOKEN}"
+`)
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected interpolation error on line 3, got %v", err)
+	}
+}
+
+func TestGitHubConfig_Targets(t *testing.T) {
+	cfg := GitHubConfig{
+		Token: "default",
+		Owner: "acme",
+		Repos: []string{"api", "partner/shared", "ACME/web"},
+		Owners: []OwnerConfig{
+			{Name: "partner", Repos: []string{"docs"}},
+			{Name: "other", Token: "other-token", Repos: []string{"billing"}},
+		},
+	}
+
+	targets := cfg.Targets()
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 targets, got %+v", targets)
+	}
+
+	expected := []OwnerTarget{
+		{Owner: "acme", Token: "default", Repos: []string{"api", "web"}},
+		{Owner: "partner", Token: "default", Repos: []string{"shared", "docs"}},
+		{Owner: "other", Token: "other-token", Repos: []string{"billing"}},
+	}
+	for i, want := range expected {
+		got := targets[i]
+		if got.Owner != want.Owner || got.Token != want.Token || strings.Join(got.Repos, ",") != strings.Join(want.Repos, ",") {
+			t.Errorf("Target %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &Config{
+		GitHub:  GitHubConfig{Token: "ghp_old", Owner: "acme", Repos: []string{"api"}},
+		Rules:   RulesConfig{ApprovalTime: 24 * time.Hour},
+		Sources: []SourceConfig{{Name: "lab", Type: "gitlab", Token: "glpat_old"}},
+	}
+	new := &Config{
+		GitHub:  GitHubConfig{Token: "ghp_new", Owner: "acme", Repos: []string{"api", "web"}},
+		Rules:   RulesConfig{ApprovalTime: 12 * time.Hour},
+		Logging: LoggingConfig{Level: "debug"},
+		Sources: []SourceConfig{{Name: "lab", Type: "gitlab", Token: "glpat_new"}, {Name: "tea", Type: "gitea"}},
+	}
+
+	want := []string{
+		"github.token: (changed)",
+		"github.repos: [api] -> [api web]",
+		"rules.approval_time: 24h0m0s -> 12h0m0s",
+		"logging.level:  -> debug",
+		"sources: 1 entries -> 2 entries",
+		"sources[0].token: (changed)",
+	}
+	if changes := Diff(old, new); !slices.Equal(changes, want) {
+		t.Errorf("Diff() = %q, want %q", changes, want)
+	}
+	if !SectionChanged(old, new, "logging") || SectionChanged(old, new, "email") {
+		t.Error("Expected only the changed sections to be reported as changed")
+	}
+	if changes := Diff(old, old); len(changes) != 0 {
+		t.Errorf("Diff of a config with itself = %q, want none", changes)
+	}
+}
+
+func TestLoad_InterpolatesScalarsOnly(t *testing.T) {
+	password := "*p@ss: #w\"o'rd\nrules:\n  approval_time: 1s"
+	t.Setenv("PRW_TEST_SMTP_PASSWORD", password)
+	t.Setenv("PRW_TEST_SMTP_PORT", "2525")
+
+	path := writeConfig(t, `
+# token: ${PRW_TEST_UNDEFINED_IN_COMMENT}
+email:
+  smtp_password: ${PRW_TEST_SMTP_PASSWORD}
+  smtp_port: ${PRW_TEST_SMTP_PORT}
+  subject: "Cost: $${PRICE}"
+  from: '${PRW_TEST_SMTP_PORT}@example.com'
+rules:
+  approval_time: "3h"
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Email.SMTPPassword != password {
+		t.Errorf("Expected the password verbatim, got %q", cfg.Email.SMTPPassword)
+	}
+	if cfg.Email.SMTPPort != 2525 {
+		t.Errorf("Expected port 2525 from a plain reference, got %d", cfg.Email.SMTPPort)
+	}
+	if cfg.Email.Subject != "Cost: ${PRICE}" || cfg.Email.From != "2525@example.com" {
+		t.Errorf("Unexpected subject %q or from %q", cfg.Email.Subject, cfg.Email.From)
+	}
+	if cfg.Rules.ApprovalTime != 3*time.Hour {
+		t.Errorf("Expected the value not to inject YAML, got approval time %v", cfg.Rules.ApprovalTime)
+	}
+}
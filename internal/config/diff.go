@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Diff returns one human-readable line per setting that differs between old
+// and new, keyed by its YAML path. Secrets are reported as changed without
+// revealing their values.
+func Diff(old, new *Config) []string {
+	var changes []string
+	diffValue("", reflect.ValueOf(*old), reflect.ValueOf(*new), &changes)
+	return changes
+}
+
+// SectionChanged reports whether the top-level section with the given YAML
+// key differs between old and new
+func SectionChanged(old, new *Config, section string) bool {
+	prefix := section + "."
+	for _, change := range Diff(old, new) {
+		if strings.HasPrefix(change, prefix) || strings.HasPrefix(change, section+":") || strings.HasPrefix(change, section+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func diffValue(path string, a, b reflect.Value, changes *[]string) {
+	if a.Kind() == reflect.Struct {
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := yamlName(field)
+			if name == "-" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValue(name, a.Field(i), b.Field(i), changes)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(a.Interface(), b.Interface()) {
+		return
+	}
+	// Compare lists of sections element by element so nested secrets stay
+	// redacted
+	if a.Kind() == reflect.Slice && a.Type().Elem().Kind() == reflect.Struct {
+		if a.Len() != b.Len() {
+			*changes = append(*changes, fmt.Sprintf("%s: %d entries -> %d entries", path, a.Len(), b.Len()))
+		}
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			diffValue(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i), changes)
+		}
+		return
+	}
+	if isSecret(path) {
+		*changes = append(*changes, fmt.Sprintf("%s: (changed)", path))
+		return
+	}
+	*changes = append(*changes, fmt.Sprintf("%s: %v -> %v", path, a.Interface(), b.Interface()))
+}
+
+func yamlName(field reflect.StructField) string {
+	tag := field.Tag.Get("yaml")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func isSecret(path string) bool {
+	p := strings.ToLower(path)
+	return strings.Contains(p, "token") || strings.Contains(p, "password") || strings.Contains(p, "secret") ||
+		strings.HasSuffix(p, "private_key")
+}
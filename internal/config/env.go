@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to the upper-cased YAML path of a setting to form
+// the environment variable that overrides it, e.g. PRW_GITHUB_TOKEN or
+// PRW_RULES_APPROVAL_TIME
+const EnvPrefix = "PRW_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// interpolationPattern matches ${NAME} and ${NAME:-default}
+var interpolationPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// interpolate replaces ${NAME} and ${NAME:-default} references in the
+// scalar values of a parsed YAML document with environment values, so that
+// values containing YAML syntax cannot change the document's structure.
+// "$${" escapes a literal "${"; comments and keys are left alone. References
+// to unset variables without a default are errors reported with their line.
+func interpolate(node *yaml.Node) error {
+	var problems []string
+	interpolateNode(node, &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("interpolation failed:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func interpolateNode(node *yaml.Node, problems *[]string) {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+		node.Value = expand(node.Value, node.Line, problems)
+		// A plain value is resolved again, so that ${PORT} can set a number
+		// or ${ENABLED} a boolean; quoted values stay strings
+		if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle|yaml.TaggedStyle) == 0 {
+			node.Tag = ""
+		}
+		return
+	}
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		interpolateNode(child, problems)
+	}
+}
+
+// expand replaces the references in a scalar value found on line
+func expand(value string, line int, problems *[]string) string {
+	parts := strings.Split(value, "$${")
+	for i, part := range parts {
+		parts[i] = interpolationPattern.ReplaceAllStringFunc(part, func(ref string) string {
+			match := interpolationPattern.FindStringSubmatch(ref)
+			if env, ok := os.LookupEnv(match[1]); ok {
+				return env
+			}
+			if strings.Contains(ref, ":-") {
+				return match[2]
+			}
+			*problems = append(*problems, fmt.Sprintf("line %d: environment variable %s is not set", line, match[1]))
+			return ""
+		})
+	}
+	return strings.Join(parts, "${")
+}
+
+// applyEnvOverrides sets every field that has a matching PRW_ environment
+// variable. Lists are comma separated. It records which env var set each path
+// so validation errors can point at it.
+func applyEnvOverrides(config *Config) error {
+	var problems []string
+	walkEnv(reflect.ValueOf(config).Elem(), "", func(path string, field reflect.Value) {
+		name := EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_").Replace(path))
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := setFromString(field, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		if config.envOverrides == nil {
+			config.envOverrides = make(map[string]string)
+		}
+		config.envOverrides[path] = name
+	})
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid environment overrides: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// walkEnv calls fn for every settable leaf field, keyed by its YAML path
+func walkEnv(v reflect.Value, path string, fn func(path string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := yamlName(field)
+		if name == "-" {
+			continue
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			walkEnv(value, name, fn)
+			continue
+		}
+		fn(name, value)
+	}
+}
+
+func setFromString(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", field.Type())
+		}
+		field.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// resolveSecretFiles replaces secrets with the contents of their *_file
+// counterparts, so credentials can be mounted by Docker, Kubernetes or
+// systemd instead of living in the config file
+func resolveSecretFiles(config *Config) error {
+	type secretFile struct {
+		path   string
+		file   string
+		target *string
+	}
+	secrets := []secretFile{
+		{"github.token_file", config.GitHub.TokenFile, &config.GitHub.Token},
+		{"email.smtp_password_file", config.Email.SMTPPasswordFile, &config.Email.SMTPPassword},
+		{"github.app.private_key_file", config.GitHub.App.PrivateKeyFile, &config.GitHub.App.PrivateKey},
+		{"webhook.secret_file", config.Webhook.SecretFile, &config.Webhook.Secret},
+		{"actions.snooze.links.secret_file", config.Actions.Snooze.Links.SecretFile, &config.Actions.Snooze.Links.Secret},
+	}
+	for i := range config.GitHub.Owners {
+		owner := &config.GitHub.Owners[i]
+		secrets = append(secrets, secretFile{fmt.Sprintf("github.owners[%d].token_file", i), owner.TokenFile, &owner.Token})
+	}
+	for i := range config.Sources {
+		source := &config.Sources[i]
+		secrets = append(secrets, secretFile{fmt.Sprintf("sources[%d].token_file", i), source.TokenFile, &source.Token})
+	}
+
+	for _, s := range secrets {
+		if s.file == "" {
+			continue
+		}
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("%s: failed to read secret file: %w", s.path, err)
+		}
+		*s.target = strings.TrimRight(string(data), "\r\n")
+	}
+	return nil
+}
@@ -0,0 +1,629 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/mail"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/schedule"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a single validation failure, located by YAML path and, when the
+// value came from a config file, by line number
+type Problem struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// ValidationError lists every problem found by Validate
+type ValidationError struct {
+	Source   string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problem(s)):", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  ")
+		switch {
+		case p.Line > 0 && e.Source != "":
+			fmt.Fprintf(&b, "%s:%d: ", e.Source, p.Line)
+		case p.Line > 0:
+			fmt.Fprintf(&b, "line %d: ", p.Line)
+		}
+		if p.Path != "" {
+			fmt.Fprintf(&b, "%s: ", p.Path)
+		}
+		b.WriteString(p.Message)
+	}
+	return b.String()
+}
+
+// validator accumulates problems, resolving line numbers from the YAML paths
+// recorded while loading
+type validator struct {
+	lines     map[string]int
+	overrides map[string]string
+	problems  []Problem
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	problem := Problem{
+		Path:    path,
+		Line:    v.line(path),
+		Message: fmt.Sprintf(format, args...),
+	}
+	// Values set from the environment have no line in the file; name the
+	// variable instead so the problem can still be located
+	if name, ok := v.overrides[path]; ok {
+		problem.Path = fmt.Sprintf("%s (from %s)", path, name)
+		problem.Line = 0
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// line returns the line of path or of its closest ancestor present in the file
+func (v *validator) line(path string) int {
+	for p := path; p != ""; {
+		if line, ok := v.lines[p]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(p, ".[")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return 0
+}
+
+// Validate checks the configuration for missing required settings, invalid
+// values and inconsistent thresholds. It reports every problem at once.
+func (c *Config) Validate() error {
+	v := &validator{lines: c.lines, overrides: c.envOverrides}
+
+	if c.GitHub.Configured() || len(c.Sources) == 0 {
+		c.validateGitHub(v)
+	}
+	c.validateSources(v)
+	c.validateEmail(v)
+	c.validateRules(v)
+	c.validateActions(v)
+
+	if c.Debug.Concurrency < 1 {
+		v.addf("debug.concurrency", "must be at least 1, got %d", c.Debug.Concurrency)
+	}
+
+	if c.Health.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Health.Listen); err != nil {
+			v.addf("health.listen", "must be a host:port address such as \":8080\": %v", err)
+		}
+	}
+	checkNonNegative(v, "health.failure_threshold", c.Health.FailureThreshold)
+	checkNonNegativeDuration(v, "health.max_run_duration", c.Health.MaxRunDuration)
+	checkNonNegativeDuration(v, "health.probe_timeout", c.Health.ProbeTimeout)
+	checkNonNegativeDuration(v, "shutdown.grace_period", c.Shutdown.GracePeriod)
+
+	if c.Webhook.Enabled {
+		if _, _, err := net.SplitHostPort(c.Webhook.Listen); err != nil {
+			v.addf("webhook.listen", "must be a host:port address such as \":8080\": %v", err)
+		}
+		if !strings.HasPrefix(c.Webhook.Path, "/") {
+			v.addf("webhook.path", "must start with /, got %q", c.Webhook.Path)
+		}
+		if c.Webhook.Secret == "" {
+			v.addf("webhook.secret", "is required when webhook.enabled is true")
+		}
+		if c.Webhook.ReconcileInterval < time.Minute {
+			v.addf("webhook.reconcile_interval", "must be at least 1m, got %v", c.Webhook.ReconcileInterval)
+		}
+		if c.Health.Listen == c.Webhook.Listen && (c.Webhook.Path == "/healthz" || c.Webhook.Path == "/readyz") {
+			v.addf("webhook.path", "%s is used by the health endpoints on the same address", c.Webhook.Path)
+		}
+	}
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "", "error", "info", "debug", "verbose":
+	default:
+		v.addf("logging.level", "must be one of error, info, debug or verbose, got %q", c.Logging.Level)
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "", "text", "json":
+	default:
+		v.addf("logging.format", "must be text or json, got %q", c.Logging.Format)
+	}
+	switch strings.ToLower(c.Logging.Output) {
+	case "", "stderr", "stdout":
+	case "file":
+		if c.Logging.File == "" {
+			v.addf("logging.file", "is required when logging.output is file")
+		}
+	default:
+		v.addf("logging.output", "must be stderr, stdout or file, got %q", c.Logging.Output)
+	}
+
+	if c.Reload.PollInterval < 0 {
+		v.addf("reload.poll_interval", "must not be negative, got %v", c.Reload.PollInterval)
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Source: c.source, Problems: v.problems}
+}
+
+func (c *Config) validateGitHub(v *validator) {
+	g := c.GitHub
+	if g.Owner == "" && g.Discovery.Enabled {
+		v.addf("github.owner", "is required when github.discovery is enabled")
+	} else if g.Owner == "" && len(g.Repos) == 0 && len(g.Owners) == 0 && !g.App.DiscoverRepos {
+		v.addf("github.owner", "is required unless github.owners or github.app.discover_repos is set")
+	}
+	if len(g.Repos) == 0 && !g.Discovery.Enabled && len(g.Owners) == 0 && !g.App.DiscoverRepos {
+		v.addf("github.repos", "must list at least one repository unless github.discovery, github.owners or github.app.discover_repos is set")
+	}
+	switch {
+	case g.App.ID < 0:
+		v.addf("github.app.id", "must be a positive GitHub App ID")
+	case g.App.ID > 0 && g.App.PrivateKey == "":
+		v.addf("github.app.private_key", "is required with github.app.id (or set private_key_file)")
+	case g.App.ID == 0 && (g.App.PrivateKey != "" || g.App.DiscoverRepos):
+		v.addf("github.app.id", "is required to authenticate as a GitHub App")
+	case g.App.PrivateKey != "" && !strings.Contains(g.App.PrivateKey, "PRIVATE KEY-----"):
+		v.addf("github.app.private_key", "must be a PEM encoded private key")
+	}
+	for i, repo := range g.Repos {
+		path := fmt.Sprintf("github.repos[%d]", i)
+		owner, name := SplitRepo(g.Owner, repo)
+		switch {
+		case strings.TrimSpace(repo) == "":
+			v.addf(path, "must not be empty")
+		case name == "" || strings.Contains(name, "/"):
+			v.addf(path, "must be a repository name or owner/repo, got %q", repo)
+		case owner == "":
+			v.addf(path, "needs an owner: use owner/repo or set github.owner")
+		}
+	}
+	switch g.API {
+	case "", "auto", "graphql", "rest":
+	default:
+		v.addf("github.api", "must be auto, graphql or rest, got %q", g.API)
+	}
+	if g.Concurrency < 0 {
+		v.addf("github.concurrency", "must not be negative")
+	}
+	for i, name := range g.Checks.Required {
+		if strings.TrimSpace(name) == "" {
+			v.addf(fmt.Sprintf("github.checks.required[%d]", i), "must not be empty")
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(g.Checks.Repos)) {
+		names := g.Checks.Repos[key]
+		if owner, repo, ok := strings.Cut(key, "/"); owner == "" || (ok && (repo == "" || strings.Contains(repo, "/"))) {
+			v.addf("github.checks.repos", "keys must be an owner or owner/repo, got %q", key)
+		}
+		for i, name := range names {
+			if strings.TrimSpace(name) == "" {
+				v.addf(fmt.Sprintf("github.checks.repos[%s][%d]", key, i), "must not be empty")
+			}
+		}
+	}
+	checkNonNegativeDuration(v, "github.checks.missing_timeout", g.Checks.MissingTimeout)
+	if g.RateLimit.MinRemaining < 0 {
+		v.addf("github.rate_limit.min_remaining", "must not be negative")
+	}
+	if g.RateLimit.MaxRetries < 0 {
+		v.addf("github.rate_limit.max_retries", "must not be negative")
+	}
+	if g.RateLimit.CacheSize < -1 {
+		v.addf("github.rate_limit.cache_size", "must be -1 (disabled) or more")
+	}
+	checkNonNegativeDuration(v, "github.timeout", g.Timeout)
+	checkNonNegativeDuration(v, "github.rate_limit.max_wait", g.RateLimit.MaxWait)
+	checkNonNegativeDuration(v, "github.rate_limit.retry_backoff", g.RateLimit.RetryBackoff)
+	checkURL(v, "github.base_url", g.BaseURL)
+	checkURL(v, "github.upload_url", g.UploadURL)
+	checkDiscovery(v, "github.discovery", g.Discovery)
+
+	seen := make(map[string]bool)
+	for i, o := range g.Owners {
+		path := fmt.Sprintf("github.owners[%d]", i)
+		if o.Name == "" {
+			v.addf(path+".name", "is required")
+		} else if seen[strings.ToLower(o.Name)] {
+			v.addf(path+".name", "owner %q is listed more than once", o.Name)
+		}
+		seen[strings.ToLower(o.Name)] = true
+		if len(o.Repos) == 0 && !o.Discovery.Enabled {
+			v.addf(path+".repos", "must list at least one repository unless discovery is enabled")
+		}
+		for j, repo := range o.Repos {
+			if strings.TrimSpace(repo) == "" || strings.Contains(repo, "/") {
+				v.addf(fmt.Sprintf("%s.repos[%d]", path, j), "must be a repository name, got %q", repo)
+			}
+		}
+		checkDiscovery(v, path+".discovery", o.Discovery)
+	}
+
+	for _, t := range g.Targets() {
+		if t.Token == "" && g.App.ID == 0 {
+			path := "github.token"
+			for i, o := range g.Owners {
+				if strings.EqualFold(o.Name, t.Owner) {
+					path = fmt.Sprintf("github.owners[%d].token", i)
+				}
+			}
+			v.addf(path, "is required (no token configured for owner %q)", t.Owner)
+		}
+	}
+}
+
+func (c *Config) validateSources(v *validator) {
+	seen := make(map[string]bool)
+	for i, s := range c.Sources {
+		path := fmt.Sprintf("sources[%d]", i)
+		if s.Name == "" {
+			v.addf(path+".name", "is required")
+		} else if seen[s.Name] {
+			v.addf(path+".name", "source %q is listed more than once", s.Name)
+		}
+		seen[s.Name] = true
+
+		switch s.Type {
+		case "gitlab", "gitea", "forgejo", "bitbucket":
+		case "":
+			v.addf(path+".type", "is required")
+		default:
+			v.addf(path+".type", "must be gitlab, gitea, forgejo or bitbucket, got %q", s.Type)
+		}
+		if s.BaseURL == "" {
+			v.addf(path+".base_url", "is required for %s sources", s.Type)
+		}
+		checkURL(v, path+".base_url", s.BaseURL)
+		if s.Token == "" {
+			v.addf(path+".token", "is required (or set token_file)")
+		}
+		if s.Owner == "" && s.Discovery.Enabled {
+			v.addf(path+".owner", "is required when discovery is enabled")
+		}
+		if len(s.Repos) == 0 && !s.Discovery.Enabled {
+			v.addf(path+".repos", "must list at least one repository unless discovery is enabled")
+		}
+		for j, repo := range s.Repos {
+			repoPath := fmt.Sprintf("%s.repos[%d]", path, j)
+			switch {
+			case strings.TrimSpace(repo) == "" || strings.HasSuffix(repo, "/"):
+				v.addf(repoPath, "must be a repository name or full path, got %q", repo)
+			case s.Owner == "" && !strings.Contains(repo, "/"):
+				v.addf(repoPath, "needs an owner: use a full path or set %s.owner", path)
+			}
+		}
+		checkDiscovery(v, path+".discovery", s.Discovery)
+		checkNonNegativeDuration(v, path+".timeout", s.Timeout)
+	}
+}
+
+func checkDiscovery(v *validator, path string, d DiscoveryConfig) {
+	for i, p := range d.Include {
+		checkPattern(v, fmt.Sprintf("%s.include[%d]", path, i), p)
+	}
+	for i, p := range d.Exclude {
+		checkPattern(v, fmt.Sprintf("%s.exclude[%d]", path, i), p)
+	}
+	switch strings.ToLower(d.Visibility) {
+	case "", "all", "public", "private", "internal":
+	default:
+		v.addf(path+".visibility", "must be all, public, private or internal, got %q", d.Visibility)
+	}
+	checkNonNegativeDuration(v, path+".refresh_interval", d.RefreshInterval)
+}
+
+// checkPattern validates a repository name glob, or a regular expression
+// when wrapped in slashes
+func checkPattern(v *validator, field, pattern string) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		if _, err := regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+			v.addf(field, "invalid regular expression %q: %v", pattern, err)
+		}
+		return
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		v.addf(field, "invalid glob pattern %q: %v", pattern, err)
+	}
+}
+
+func (c *Config) validateEmail(v *validator) {
+	if !c.Debug.SkipEmails {
+		if c.Email.SMTPHost == "" {
+			v.addf("email.smtp_host", "is required unless debug.skip_emails is set")
+		}
+		if c.Email.SMTPPort == 0 {
+			v.addf("email.smtp_port", "is required unless debug.skip_emails is set")
+		}
+		if c.Email.From == "" {
+			v.addf("email.from", "is required unless debug.skip_emails is set")
+		}
+		if len(c.Email.To) == 0 {
+			v.addf("email.to", "must list at least one recipient unless debug.skip_emails is set")
+		}
+	}
+	if c.Email.SMTPPort < 0 || c.Email.SMTPPort > 65535 {
+		v.addf("email.smtp_port", "must be between 1 and 65535, got %d", c.Email.SMTPPort)
+	}
+	checkAddress(v, "email.from", c.Email.From)
+	for i, to := range c.Email.To {
+		checkAddress(v, fmt.Sprintf("email.to[%d]", i), to)
+	}
+	for _, login := range slices.Sorted(maps.Keys(c.Email.Users)) {
+		checkAddress(v, "email.users."+login, c.Email.Users[login])
+	}
+	checkNonNegativeDuration(v, "email.rate_limit", c.Email.RateLimit)
+	checkNonNegativeDuration(v, "email.rate_timeout", c.Email.RateTimeout)
+	checkNonNegativeDuration(v, "email.timeout", c.Email.Timeout)
+}
+
+func (c *Config) validateRules(v *validator) {
+	r := c.Rules
+	global := PRTimeRules{
+		ApprovalTime:      r.ApprovalTime,
+		MergeReminderTime: r.MergeReminderTime,
+		MergeTime:         r.MergeTime,
+		DraftTime:         r.DraftTime,
+	}
+	checkTimeRules(v, "rules", global)
+
+	if r.CheckInterval <= 0 && r.Schedule == "" {
+		v.addf("rules.check_interval", "must be positive, got %v", r.CheckInterval)
+	}
+	checkNonNegativeDuration(v, "rules.jitter", r.Jitter)
+	if _, err := schedule.New(schedule.Options{
+		Cron:        r.Schedule,
+		Interval:    r.CheckInterval,
+		ActiveHours: r.ActiveHours,
+		ActiveDays:  r.ActiveDays,
+		Timezone:    r.Timezone,
+	}); err != nil && (r.Schedule != "" || r.CheckInterval > 0) {
+		path := "rules.schedule"
+		switch {
+		case r.Schedule == "" && r.ActiveHours != "":
+			path = "rules.active_hours"
+		case r.Schedule == "" && len(r.ActiveDays) > 0:
+			path = "rules.active_days"
+		case r.Schedule == "" && r.Timezone != "":
+			path = "rules.timezone"
+		}
+		v.addf(path, "%v", err)
+	}
+
+	checkAddress(v, "rules.escalation_email", r.EscalationEmail)
+	checkNonNegativeDuration(v, "rules.inactivity.waiting_on_reviewer", r.Inactivity.WaitingOnReviewer)
+	checkNonNegativeDuration(v, "rules.inactivity.waiting_on_author", r.Inactivity.WaitingOnAuthor)
+
+	t := r.PRSize.Thresholds
+	if t.XS < 0 || t.S < t.XS || t.M < t.S || t.L < t.M || t.XL < t.L {
+		v.addf("rules.pr_size.thresholds", "must be non-negative and ascending (xs <= s <= m <= l <= xl), got %d/%d/%d/%d/%d",
+			t.XS, t.S, t.M, t.L, t.XL)
+	}
+
+	// Sizes without their own times inherit the global rules, which have
+	// already been checked above
+	for _, size := range []struct {
+		name  string
+		times PRTimeRules
+	}{
+		{"xs", r.PRSize.Times.XS},
+		{"s", r.PRSize.Times.S},
+		{"m", r.PRSize.Times.M},
+		{"l", r.PRSize.Times.L},
+		{"xl", r.PRSize.Times.XL},
+	} {
+		if size.times != global {
+			checkTimeRules(v, "rules.pr_size.times."+size.name, size.times)
+		}
+	}
+}
+
+// validateActions checks the pull request actions
+func (c *Config) validateActions(v *validator) {
+	comments := c.Actions.Comments
+	for i, event := range comments.Events {
+		if !slices.Contains(CommentEvents, event) {
+			v.addf(fmt.Sprintf("actions.comments.events[%d]", i), "must be one of %s, got %q", strings.Join(CommentEvents, ", "), event)
+		}
+	}
+	for i, name := range comments.Reviewers {
+		if strings.TrimPrefix(name, "@") == "" {
+			v.addf(fmt.Sprintf("actions.comments.reviewers[%d]", i), "must not be empty")
+		}
+	}
+	for i, name := range comments.EscalationMentions {
+		if strings.TrimPrefix(name, "@") == "" {
+			v.addf(fmt.Sprintf("actions.comments.escalation_mentions[%d]", i), "must not be empty")
+		}
+	}
+
+	labels := c.Actions.Labels
+	for _, l := range []struct {
+		path  string
+		label LabelConfig
+	}{
+		{"actions.labels.needs_review", labels.NeedsReview},
+		{"actions.labels.overdue", labels.Overdue},
+		{"actions.labels.escalated", labels.Escalated},
+		{"actions.labels.size", labels.Size},
+		{"actions.drafts.label", c.Actions.Drafts.Label},
+	} {
+		if !labelColor.MatchString(l.label.Color) {
+			v.addf(l.path+".color", "must be six hex digits such as \"d93f0b\", got %q", l.label.Color)
+		}
+	}
+	if labels.NeedsReview.Name == labels.Overdue.Name || labels.NeedsReview.Name == labels.Escalated.Name || labels.Overdue.Name == labels.Escalated.Name {
+		v.addf("actions.labels", "needs_review, overdue and escalated must have different names")
+	}
+
+	drafts := c.Actions.Drafts
+	checkNonNegativeDuration(v, "actions.drafts.warn_after", drafts.WarnAfter)
+	if drafts.Close && drafts.CloseAfter <= drafts.WarnAfter {
+		v.addf("actions.drafts.close_after", "(%v) must be greater than warn_after (%v), so that authors are warned before their draft is closed",
+			drafts.CloseAfter, drafts.WarnAfter)
+	}
+
+	reviewers := c.Actions.Reviewers
+	switch reviewers.Strategy {
+	case "round_robin", "least_loaded":
+		if reviewers.Enabled && len(reviewers.Team) == 0 {
+			v.addf("actions.reviewers.team", "is required for the %s strategy", reviewers.Strategy)
+		}
+	case "codeowners":
+	default:
+		v.addf("actions.reviewers.strategy", "must be round_robin, least_loaded or codeowners, got %q", reviewers.Strategy)
+	}
+	if reviewers.Enabled && len(reviewers.Repos) == 0 {
+		v.addf("actions.reviewers.repos", "is required when actions.reviewers.enabled is true")
+	}
+	for i, repo := range reviewers.Repos {
+		if _, err := path.Match(repo, ""); err != nil || repo == "" {
+			v.addf(fmt.Sprintf("actions.reviewers.repos[%d]", i), "must be a repository name or glob pattern, got %q", repo)
+		}
+	}
+	for i, login := range reviewers.Team {
+		if login == "" {
+			v.addf(fmt.Sprintf("actions.reviewers.team[%d]", i), "must not be empty")
+		}
+	}
+	if reviewers.Count < 1 {
+		v.addf("actions.reviewers.count", "must be at least 1, got %d", reviewers.Count)
+	}
+
+	snooze := c.Actions.Snooze
+	if snooze.Default <= 0 {
+		v.addf("actions.snooze.default", "must be positive, got %v", snooze.Default)
+	}
+	if snooze.Max < snooze.Default {
+		v.addf("actions.snooze.max", "(%v) must not be less than default (%v)", snooze.Max, snooze.Default)
+	}
+	for i, login := range snooze.Users {
+		if strings.TrimPrefix(login, "@") == "" {
+			v.addf(fmt.Sprintf("actions.snooze.users[%d]", i), "must not be empty")
+		}
+	}
+	if links := snooze.Links; links.Enabled {
+		if links.BaseURL == "" {
+			v.addf("actions.snooze.links.base_url", "is required when actions.snooze.links.enabled is true")
+		}
+		checkURL(v, "actions.snooze.links.base_url", links.BaseURL)
+		if _, _, err := net.SplitHostPort(links.Listen); err != nil {
+			v.addf("actions.snooze.links.listen", "must be a host:port address such as \":8080\": %v", err)
+		}
+		switch {
+		case !strings.HasPrefix(links.Path, "/"):
+			v.addf("actions.snooze.links.path", "must start with /, got %q", links.Path)
+		case c.Health.Listen == links.Listen && (links.Path == "/healthz" || links.Path == "/readyz"):
+			v.addf("actions.snooze.links.path", "%s is used by the health endpoints on the same address", links.Path)
+		case c.Webhook.Enabled && c.Webhook.Listen == links.Listen && c.Webhook.Path == links.Path:
+			v.addf("actions.snooze.links.path", "%s is used by the webhook receiver on the same address", links.Path)
+		}
+		if links.Secret == "" {
+			v.addf("actions.snooze.links.secret", "is required when actions.snooze.links.enabled is true")
+		}
+		for i, d := range links.Durations {
+			if d <= 0 || d > snooze.Max {
+				v.addf(fmt.Sprintf("actions.snooze.links.durations[%d]", i), "must be positive and at most max (%v), got %v", snooze.Max, d)
+			}
+		}
+		if links.ValidFor <= 0 {
+			v.addf("actions.snooze.links.valid_for", "must be positive, got %v", links.ValidFor)
+		}
+	}
+}
+
+var labelColor = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// checkTimeRules verifies that thresholds are non-negative and ordered so
+// that every notification stage can actually be reached
+func checkTimeRules(v *validator, path string, t PRTimeRules) {
+	checkNonNegativeDuration(v, path+".approval_time", t.ApprovalTime)
+	checkNonNegativeDuration(v, path+".merge_reminder_time", t.MergeReminderTime)
+	checkNonNegativeDuration(v, path+".merge_time", t.MergeTime)
+	checkNonNegativeDuration(v, path+".draft_time", t.DraftTime)
+
+	if t.MergeTime > 0 && t.ApprovalTime > t.MergeTime {
+		v.addf(path+".approval_time", "(%v) must not exceed merge_time (%v), otherwise approval reminders are never sent",
+			t.ApprovalTime, t.MergeTime)
+	}
+	if t.MergeTime > 0 && t.MergeReminderTime > t.MergeTime {
+		v.addf(path+".merge_reminder_time", "(%v) must not exceed merge_time (%v), otherwise merge reminders are never sent",
+			t.MergeReminderTime, t.MergeTime)
+	}
+}
+
+func checkNonNegative(v *validator, path string, n int) {
+	if n < 0 {
+		v.addf(path, "must not be negative, got %d", n)
+	}
+}
+
+func checkNonNegativeDuration(v *validator, path string, d time.Duration) {
+	if d < 0 {
+		v.addf(path, "must not be negative, got %v", d)
+	}
+}
+
+func checkAddress(v *validator, path, address string) {
+	if address == "" {
+		return
+	}
+	if _, err := mail.ParseAddress(address); err != nil {
+		v.addf(path, "invalid email address %q: %v", address, err)
+	}
+}
+
+func checkURL(v *validator, path, raw string) {
+	if raw == "" {
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		v.addf(path, "invalid URL %q: %v", raw, err)
+		return
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(path, "must be an absolute http(s) URL, got %q", raw)
+	}
+}
+
+// recordLines maps every YAML path in the document to the line it starts on
+func recordLines(node *yaml.Node, path string, lines map[string]int) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			recordLines(child, path, lines)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			lines[childPath] = key.Line
+			recordLines(value, childPath, lines)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			lines[childPath] = item.Line
+			recordLines(item, childPath, lines)
+		}
+	}
+}
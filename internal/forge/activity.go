@@ -0,0 +1,148 @@
+package forge
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Whose turn it is on a pull request, as returned by PullRequest.Waiting
+// and PullRequest.BallInCourt
+const (
+	WaitingOnReviewer = "reviewer"
+	WaitingOnAuthor   = "author"
+	WaitingOnCI       = "ci"    // Approved, waiting for its checks to finish
+	WaitingOnMerge    = "merge" // Approved, waiting for someone to merge it
+)
+
+// Activity is when a PR last saw each kind of activity. Times are zero when
+// unknown: pushes and comments are only known from the timeline.
+type Activity struct {
+	LastPush           time.Time // Last commit or force push
+	LastReview         time.Time // Last review by someone other than the author
+	LastComment        time.Time // Last comment by anyone
+	LastAuthorResponse time.Time // Last push or comment of the author after a review, zero if none
+}
+
+// Activity summarizes the reviews and timeline of a PR
+func (pr *PullRequest) Activity() Activity {
+	var a Activity
+	for _, review := range pr.Reviews {
+		if !pr.isAuthor(review.User) && review.SubmittedAt.After(a.LastReview) {
+			a.LastReview = review.SubmittedAt
+		}
+	}
+	for _, event := range pr.Timeline {
+		switch event.Type {
+		case "commit", "force_push":
+			a.LastPush = latest(a.LastPush, event.CreatedAt)
+		case "comment":
+			a.LastComment = latest(a.LastComment, event.CreatedAt)
+		case "review":
+			if !pr.isAuthor(event.Actor) {
+				a.LastReview = latest(a.LastReview, event.CreatedAt)
+			}
+		}
+	}
+
+	if a.LastReview.IsZero() {
+		return a
+	}
+	// Pushes count as the author's, whoever committed
+	response := pr.authorActivity()
+	if response.After(a.LastReview) {
+		a.LastAuthorResponse = response
+	}
+	return a
+}
+
+// Waiting reports whose turn it is on a PR and since when. It is the
+// author's when the last review asked for changes or left comments after
+// the author last pushed or commented, and the reviewers' otherwise, since
+// the PR was opened, marked ready, reviewed or updated by its author.
+func (pr *PullRequest) Waiting() (string, time.Time) {
+	var last *Review
+	for i, review := range pr.Reviews {
+		if !pr.isAuthor(review.User) && (last == nil || review.SubmittedAt.After(last.SubmittedAt)) {
+			last = &pr.Reviews[i]
+		}
+	}
+	author := pr.authorActivity()
+	if last != nil && (last.State == "CHANGES_REQUESTED" || last.State == "COMMENTED") && last.SubmittedAt.After(author) {
+		return WaitingOnAuthor, last.SubmittedAt
+	}
+
+	since := latest(pr.CreatedAt, author)
+	if last != nil {
+		since = latest(since, last.SubmittedAt)
+	}
+	for _, event := range pr.Timeline {
+		if event.Type == "ready_for_review" {
+			since = latest(since, event.CreatedAt)
+		}
+	}
+	return WaitingOnReviewer, since
+}
+
+// BallInCourt reports whose turn it is on a PR and since when, like Waiting,
+// but also covering drafts, which are the author's, and PRs with the two
+// reviews the watcher asks for. Unless the last review asked for changes,
+// those wait on their checks while they run, on the author when they
+// failed, and to be merged otherwise.
+func (pr *PullRequest) BallInCourt() (string, time.Time) {
+	if pr.Draft {
+		return WaitingOnAuthor, latest(pr.CreatedAt, pr.authorActivity())
+	}
+	who, since := pr.Waiting()
+	if who != WaitingOnReviewer || pr.ReviewCount < 2 {
+		return who, since
+	}
+	switch pr.CIState() {
+	case CIPending:
+		return WaitingOnCI, since
+	case CIFailing:
+		return WaitingOnAuthor, since
+	default:
+		return WaitingOnMerge, since
+	}
+}
+
+// Reviewers returns the requested reviewers of a PR, or those who reviewed
+// it when nobody is requested
+func (pr *PullRequest) Reviewers() []string {
+	if len(pr.RequestedReviewers) > 0 {
+		return pr.RequestedReviewers
+	}
+	var reviewers []string
+	for _, review := range pr.Reviews {
+		if !pr.isAuthor(review.User) && !slices.Contains(reviewers, review.User) {
+			reviewers = append(reviewers, review.User)
+		}
+	}
+	return reviewers
+}
+
+// authorActivity returns when the author last pushed or commented
+func (pr *PullRequest) authorActivity() time.Time {
+	var last time.Time
+	for _, event := range pr.Timeline {
+		switch {
+		case event.Type == "commit", event.Type == "force_push":
+			last = latest(last, event.CreatedAt)
+		case event.Type == "comment" && pr.isAuthor(event.Actor):
+			last = latest(last, event.CreatedAt)
+		}
+	}
+	return last
+}
+
+func (pr *PullRequest) isAuthor(login string) bool {
+	return pr.User != nil && login != "" && strings.EqualFold(login, pr.User.Login)
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
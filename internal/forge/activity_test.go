@@ -0,0 +1,140 @@
+package forge
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaiting(t *testing.T) {
+	opened := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return opened.Add(time.Duration(hours) * time.Hour) }
+	author := &User{Login: "alice"}
+
+	tests := []struct {
+		name      string
+		pr        PullRequest
+		wantWho   string
+		wantSince time.Time
+	}{
+		{
+			name:      "new PR waits on reviewers since it was opened",
+			pr:        PullRequest{CreatedAt: opened, User: author},
+			wantWho:   WaitingOnReviewer,
+			wantSince: opened,
+		},
+		{
+			name: "changes requested wait on the author",
+			pr: PullRequest{CreatedAt: opened, User: author,
+				Reviews:  []Review{{User: "bob", State: "CHANGES_REQUESTED", SubmittedAt: at(5)}},
+				Timeline: []TimelineEvent{{Type: "commit", Actor: "alice", CreatedAt: at(2)}}},
+			wantWho:   WaitingOnAuthor,
+			wantSince: at(5),
+		},
+		{
+			name: "a push after the review hands it back to reviewers",
+			pr: PullRequest{CreatedAt: opened, User: author,
+				Reviews:  []Review{{User: "bob", State: "CHANGES_REQUESTED", SubmittedAt: at(5)}},
+				Timeline: []TimelineEvent{{Type: "force_push", Actor: "alice", CreatedAt: at(8)}}},
+			wantWho:   WaitingOnReviewer,
+			wantSince: at(8),
+		},
+		{
+			name: "the author's own comments and reviews are responses",
+			pr: PullRequest{CreatedAt: opened, User: author,
+				Reviews: []Review{{User: "bob", State: "COMMENTED", SubmittedAt: at(5)}, {User: "alice", State: "COMMENTED", SubmittedAt: at(7)}},
+				Timeline: []TimelineEvent{
+					{Type: "comment", Actor: "carol", CreatedAt: at(6)},
+					{Type: "comment", Actor: "Alice", CreatedAt: at(7)},
+				}},
+			wantWho:   WaitingOnReviewer,
+			wantSince: at(7),
+		},
+		{
+			name: "one approval of two still waits on reviewers",
+			pr: PullRequest{CreatedAt: opened, User: author,
+				Reviews: []Review{{User: "bob", State: "APPROVED", SubmittedAt: at(3)}}},
+			wantWho:   WaitingOnReviewer,
+			wantSince: at(3),
+		},
+	}
+	for _, tt := range tests {
+		who, since := tt.pr.Waiting()
+		if who != tt.wantWho || !since.Equal(tt.wantSince) {
+			t.Errorf("%s: Waiting() = %s since %v, want %s since %v", tt.name, who, since, tt.wantWho, tt.wantSince)
+		}
+	}
+}
+
+func TestActivity(t *testing.T) {
+	opened := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return opened.Add(time.Duration(hours) * time.Hour) }
+	pr := PullRequest{
+		CreatedAt: opened,
+		User:      &User{Login: "alice"},
+		Reviews:   []Review{{User: "bob", State: "CHANGES_REQUESTED", SubmittedAt: at(4)}},
+		Timeline: []TimelineEvent{
+			{Type: "commit", Actor: "alice", CreatedAt: at(1)},
+			{Type: "comment", Actor: "bob", CreatedAt: at(3)},
+			{Type: "comment", Actor: "alice", CreatedAt: at(6)},
+		},
+	}
+	want := Activity{LastPush: at(1), LastReview: at(4), LastComment: at(6), LastAuthorResponse: at(6)}
+	if got := pr.Activity(); got != want {
+		t.Errorf("Activity() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBallInCourt(t *testing.T) {
+	opened := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return opened.Add(time.Duration(hours) * time.Hour) }
+	author := &User{Login: "alice"}
+	approvals := []Review{{User: "bob", State: "APPROVED", SubmittedAt: at(3)}, {User: "carol", State: "APPROVED", SubmittedAt: at(4)}}
+
+	tests := []struct {
+		name      string
+		pr        PullRequest
+		wantWho   string
+		wantSince time.Time
+	}{
+		{
+			name: "drafts are the author's",
+			pr: PullRequest{CreatedAt: opened, User: author, Draft: true,
+				Timeline: []TimelineEvent{{Type: "commit", Actor: "alice", CreatedAt: at(2)}}},
+			wantWho:   WaitingOnAuthor,
+			wantSince: at(2),
+		},
+		{
+			name:      "two approvals wait to be merged",
+			pr:        PullRequest{CreatedAt: opened, User: author, Reviews: approvals, ReviewCount: 2},
+			wantWho:   WaitingOnMerge,
+			wantSince: at(4),
+		},
+		{
+			name: "approved with checks running waits on CI",
+			pr: PullRequest{CreatedAt: opened, User: author, Reviews: approvals, ReviewCount: 2,
+				Checks: []Check{{Name: "build", State: CIPassing}, {Name: "e2e", State: CIPending}}},
+			wantWho:   WaitingOnCI,
+			wantSince: at(4),
+		},
+		{
+			name: "approved with a failed required check waits on the author",
+			pr: PullRequest{CreatedAt: opened, User: author, Reviews: approvals, ReviewCount: 2,
+				Checks: []Check{{Name: "build", State: CIFailing, Required: true}, {Name: "e2e", State: CIPending}}},
+			wantWho:   WaitingOnAuthor,
+			wantSince: at(4),
+		},
+		{
+			name: "changes requested after approvals wait on the author",
+			pr: PullRequest{CreatedAt: opened, User: author, ReviewCount: 3,
+				Reviews: append(approvals, Review{User: "dave", State: "CHANGES_REQUESTED", SubmittedAt: at(5)})},
+			wantWho:   WaitingOnAuthor,
+			wantSince: at(5),
+		},
+	}
+	for _, tt := range tests {
+		who, since := tt.pr.BallInCourt()
+		if who != tt.wantWho || !since.Equal(tt.wantSince) {
+			t.Errorf("%s: BallInCourt() = %s since %v, want %s since %v", tt.name, who, since, tt.wantWho, tt.wantSince)
+		}
+	}
+}
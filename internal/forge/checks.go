@@ -0,0 +1,90 @@
+package forge
+
+import (
+	"slices"
+	"time"
+)
+
+// CI states of the head commit of a pull request, as returned by
+// PullRequest.CIState
+const (
+	CIUnknown = "" // No checks, or they were not fetched
+	CIPending = "pending"
+	CIFailing = "failing"
+	CIPassing = "passing"
+)
+
+// Check is a commit status or check run on the head commit of a PR.
+// Forges report a required check that has not run yet as pending, with
+// Since set, until Timeout has passed.
+type Check struct {
+	Name     string        `json:"name"`
+	State    string        `json:"state"`              // pending, failing or passing
+	Required bool          `json:"required,omitempty"` // One of the checks configured as required
+	Since    time.Time     `json:"since,omitempty"`    // When a required check that has not reported was expected
+	Timeout  time.Duration `json:"timeout,omitempty"`  // How long a required check may take to report
+}
+
+// Missing reports whether check is a required check that has not reported
+// within its timeout
+func (check Check) Missing() bool {
+	return check.Required && !check.Since.IsZero() && time.Since(check.Since) >= check.Timeout
+}
+
+// counted returns the checks that decide the CI state of a PR: the required
+// ones other than missing ones, or all of them when none is required
+func (pr *PullRequest) counted() []Check {
+	if !slices.ContainsFunc(pr.Checks, func(check Check) bool { return check.Required }) {
+		return pr.Checks
+	}
+	var required []Check
+	for _, check := range pr.Checks {
+		if check.Required && !check.Missing() {
+			required = append(required, check)
+		}
+	}
+	return required
+}
+
+// CIState returns the state of the checks of a PR: failing when any of them
+// failed, otherwise pending while any is still running
+func (pr *PullRequest) CIState() string {
+	state := CIUnknown
+	for _, check := range pr.counted() {
+		switch check.State {
+		case CIFailing:
+			return CIFailing
+		case CIPending:
+			state = CIPending
+		case CIPassing:
+			if state == CIUnknown {
+				state = CIPassing
+			}
+		}
+	}
+	return state
+}
+
+// FailingChecks returns the names of the failed checks that make CIState
+// failing
+func (pr *PullRequest) FailingChecks() []string {
+	var names []string
+	for _, check := range pr.counted() {
+		if check.State == CIFailing {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
+
+// MissingChecks returns the names of the required checks that have not
+// reported within their timeout and are ignored
+func (pr *PullRequest) MissingChecks() []string {
+	var names []string
+	for _, check := range pr.Checks {
+		if check.Missing() {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,92 @@
+package forge
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// CodeOwners is a parsed CODEOWNERS file
+type CodeOwners []codeOwnersRule
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// ParseCodeOwners parses a CODEOWNERS file. Owners are returned without the
+// leading @, so teams read "org/team"; email addresses are skipped. Lines
+// with a pattern that cannot be parsed are ignored, as GitHub does.
+func ParseCodeOwners(data []byte) CodeOwners {
+	var rules CodeOwners
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			continue
+		}
+		rule := codeOwnersRule{pattern: pattern}
+		for _, owner := range fields[1:] {
+			if name, ok := strings.CutPrefix(owner, "@"); ok && name != "" {
+				rule.owners = append(rule.owners, name)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Owners returns the owners of a file path. As in CODEOWNERS, the last
+// matching rule wins, and a rule without owners leaves the file unowned.
+func (c CodeOwners) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].pattern.MatchString(path) {
+			return c[i].owners
+		}
+	}
+	return nil
+}
+
+// codeOwnersPattern converts a gitignore-style CODEOWNERS pattern into a
+// regular expression. Patterns with a leading or inner slash are relative to
+// the repository root, others match at any depth, and a pattern matching a
+// directory matches everything below it.
+func codeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.Trim(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("(?:/.*)?$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,35 @@
+package forge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeOwners_Owners(t *testing.T) {
+	owners := ParseCodeOwners([]byte(`
+# Default owners
+*            @acme/core
+*.go         @alice  # Go code
+/docs/       @acme/docs-team docs@example.com
+apps/**/api  @bob
+/build/logs
+`))
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"acme/core"}},
+		{"cmd/main.go", []string{"alice"}},
+		{"docs/guide/intro.md", []string{"acme/docs-team"}},
+		{"src/docs/intro.md", []string{"acme/core"}},
+		{"apps/web/v2/api/handler.ts", []string{"bob"}},
+		{"apps/api/routes.ts", []string{"bob"}},
+		{"build/logs/output.txt", nil},
+	}
+	for _, tt := range tests {
+		if got := owners.Owners(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Owners(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
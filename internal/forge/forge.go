@@ -0,0 +1,254 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Forge lists the open change requests of a code host. GitHub pull
+// requests, GitLab merge requests and the like are all mapped onto
+// PullRequest, so rules and notifications work the same for every forge.
+type Forge interface {
+	// GetPullRequests returns the open pull requests of repos of owner.
+	// When only some repositories fail it returns the pull requests of the
+	// others together with FetchErrors.
+	GetPullRequests(ctx context.Context, owner string, repos []string) ([]*PullRequest, error)
+
+	// GetPRDetails returns a single pull request with its reviews
+	GetPRDetails(ctx context.Context, owner, repo string, number int) (*PullRequest, error)
+
+	// ListRepositories lists the repositories of owner for discovery
+	ListRepositories(ctx context.Context, owner string) ([]*Repository, error)
+
+	// Ping checks that the forge is reachable and the credentials work
+	Ping(ctx context.Context) error
+}
+
+// Commenter is implemented by forges that can keep a comment on a pull
+// request up to date
+type Commenter interface {
+	// UpsertComment replaces the body of the pull request's comment that
+	// contains marker, or adds a new comment if there is none
+	UpsertComment(ctx context.Context, owner, repo string, number int, marker, body string) error
+}
+
+// Labeler is implemented by forges that can label pull requests
+type Labeler interface {
+	// CreateLabel creates a repository label, unless one with that name
+	// already exists. color is six hex digits.
+	CreateLabel(ctx context.Context, owner, repo, name, color string) error
+
+	// AddLabels adds existing repository labels to a pull request
+	AddLabels(ctx context.Context, owner, repo string, number int, labels []string) error
+
+	// RemoveLabel removes a label from a pull request
+	RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error
+}
+
+// ReviewRequester is implemented by forges that can request reviews on pull
+// requests
+type ReviewRequester interface {
+	// RequestReviewers requests reviews from users, or from teams given as
+	// "org/team"
+	RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers []string) error
+
+	// CodeOwners returns the code owners of the files a pull request
+	// changes, according to the CODEOWNERS file of its base branch
+	CodeOwners(ctx context.Context, pr *PullRequest) ([]string, error)
+}
+
+// Closer is implemented by forges that can close pull requests
+type Closer interface {
+	// ClosePullRequest closes a pull request without merging it
+	ClosePullRequest(ctx context.Context, owner, repo string, number int) error
+}
+
+// PullRequest represents a pull request with additional metadata
+type PullRequest struct {
+	Number       int       `json:"number"`
+	Title        string    `json:"title"`
+	State        string    `json:"state"`
+	Draft        bool      `json:"draft"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	User         *User     `json:"user"`
+	Head         *Branch   `json:"head"`
+	Base         *Branch   `json:"base"`
+	URL          string    `json:"html_url"`
+	Approved     bool      `json:"approved"`
+	ReviewCount  int       `json:"review_count"`
+	Repo         string    `json:"repo"`
+	Owner        string    `json:"owner"`            // For GitLab the full group path, e.g. "group/subgroup"
+	Source       string    `json:"source,omitempty"` // Name of the configured source, empty for GitHub
+	Additions    int       `json:"additions"`
+	Deletions    int       `json:"deletions"`
+	TotalChanges int       `json:"total_changes"`
+	ChangedFiles int       `json:"changed_files"`
+	SizeCategory string    `json:"size_category"` // XS, S, M, L, XL
+
+	Labels             []string        `json:"labels"`
+	RequestedReviewers []string        `json:"requested_reviewers"` // User logins and team slugs
+	Reviews            []Review        `json:"reviews"`
+	Timeline           []TimelineEvent `json:"timeline"`         // Only filled by the GitHub GraphQL fetcher and webhooks
+	Checks             []Check         `json:"checks,omitempty"` // Checks of the head commit, only filled with github.checks
+}
+
+// Review is a submitted review of a PR
+type Review struct {
+	ID          int64     `json:"id"`
+	User        string    `json:"user"`
+	State       string    `json:"state"` // APPROVED, CHANGES_REQUESTED, COMMENTED or DISMISSED
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// TimelineEvent is an activity on a PR, used to tell who acted last
+type TimelineEvent struct {
+	Type      string    `json:"type"` // commit, force_push, comment, review, ready_for_review or review_requested
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+	Command   string    `json:"command,omitempty"` // The /pr-watcher command of a comment, if any
+}
+
+// CommandPrefix starts the lines of comments addressed to the watcher, such
+// as "/pr-watcher snooze 2d"
+const CommandPrefix = "/pr-watcher"
+
+// MarkerPrefix starts the hidden HTML comment that marks the comments the
+// watcher writes, such as "<!-- pr-watcher:reminder -->"
+const MarkerPrefix = "<!-- pr-watcher:"
+
+// IsWatcherComment reports whether a comment body is one the watcher wrote.
+// It goes by the marker rather than the author, since the watcher often
+// runs with the token of a person whose own comments count.
+func IsWatcherComment(body string) bool {
+	return strings.Contains(body, MarkerPrefix)
+}
+
+// FindCommand returns the first line of a comment body that is a watcher
+// command, without surrounding space, or "" if there is none
+func FindCommand(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == CommandPrefix || strings.HasPrefix(line, CommandPrefix+" ") {
+			return line
+		}
+	}
+	return ""
+}
+
+// FullName returns the "owner/repo" name of the PR's repository
+func (pr *PullRequest) FullName() string {
+	if pr.Owner == "" {
+		return pr.Repo
+	}
+	return pr.Owner + "/" + pr.Repo
+}
+
+// SetReviews replaces the reviews of a PR and updates Approved and
+// ReviewCount to match
+func (pr *PullRequest) SetReviews(reviews []Review) {
+	pr.Reviews = reviews
+	pr.Approved, pr.ReviewCount = SummarizeReviews(reviews)
+}
+
+// SetSize records the diff stats of a PR and derives its size category
+func (pr *PullRequest) SetSize(additions, deletions, changedFiles int) {
+	pr.Additions = additions
+	pr.Deletions = deletions
+	pr.TotalChanges = additions + deletions
+	pr.ChangedFiles = changedFiles
+	pr.SizeCategory = SizeCategory(pr.TotalChanges)
+}
+
+// User represents a forge user
+type User struct {
+	Login string `json:"login"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// Branch represents a Git branch
+type Branch struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+// Repository is a repository returned by discovery
+type Repository struct {
+	Owner      string   `json:"owner"`
+	Name       string   `json:"name"`
+	Topics     []string `json:"topics"`
+	Visibility string   `json:"visibility"` // public, private or internal
+	Archived   bool     `json:"archived"`
+	Fork       bool     `json:"fork"`
+}
+
+// SizeCategory maps the number of changed lines to XS, S, M, L or XL
+func SizeCategory(totalChanges int) string {
+	switch {
+	case totalChanges <= 50:
+		return "XS"
+	case totalChanges <= 200:
+		return "S"
+	case totalChanges <= 500:
+		return "M"
+	case totalChanges <= 1000:
+		return "L"
+	default:
+		return "XL"
+	}
+}
+
+// SummarizeReviews reports whether a PR has been approved and how many
+// reviews other than plain comments it has
+func SummarizeReviews(reviews []Review) (bool, int) {
+	approved := false
+	reviewCount := 0
+
+	for _, review := range reviews {
+		if review.State == "APPROVED" {
+			approved = true
+		}
+		if review.State != "COMMENTED" {
+			reviewCount++
+		}
+	}
+
+	return approved, reviewCount
+}
+
+// RepoError is a failure to fetch the pull requests of one repository
+type RepoError struct {
+	Owner string
+	Repo  string
+	Err   error
+}
+
+func (e *RepoError) Error() string {
+	return fmt.Sprintf("failed to get PRs for repo %s/%s: %v", e.Owner, e.Repo, e.Err)
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// FetchErrors is returned by GetPullRequests, together with the pull
+// requests of the healthy repositories, when some repositories failed
+type FetchErrors []*RepoError
+
+func (e FetchErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("failed to get PRs for %d repositories (first: %v)", len(e), e[0])
+}
+
+func (e FetchErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
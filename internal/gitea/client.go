@@ -0,0 +1,398 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+// pageSize is the number of items requested per page. Instances cap it at
+// their MAX_RESPONSE_ITEMS setting (default 50), so the end of a list is
+// told by the X-Total-Count header where available.
+const pageSize = 50
+
+// wipPrefixes are the title prefixes Gitea and Forgejo treat as work in
+// progress by default. Versions before draft support only know these.
+var wipPrefixes = []string{"wip:", "[wip]", "draft:", "[draft]"}
+
+// Client lists pull requests of a Gitea or Forgejo instance through its
+// REST API (v1), which both share
+type Client struct {
+	baseURL string // API root, e.g. https://gitea.example.com/api/v1/
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for a gitea or forgejo source
+func NewClient(cfg config.SourceConfig) (*Client, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("Gitea token is required")
+	}
+	if cfg.BaseURL == "" {
+		return nil, errors.New("Gitea base URL is required")
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/") + "/api/v1/",
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// APIError is a non-2xx response of the Gitea API
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Gitea API returned %d", e.Status)
+	}
+	return fmt.Sprintf("Gitea API returned %d: %s", e.Status, e.Message)
+}
+
+// get fetches path and decodes the JSON response into v. The total number
+// of items is returned for paginated endpoints, or -1 when not reported.
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) (int, error) {
+	resp, err := c.do(ctx, path, query, "application/json")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return 0, fmt.Errorf("invalid Gitea API response for %s: %w", path, err)
+	}
+	total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		return -1, nil
+	}
+	return total, nil
+}
+
+func (c *Client) do(ctx context.Context, path string, query url.Values, accept string) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+	req.Header.Set("Accept", accept)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+		return nil, &APIError{Status: resp.StatusCode, Message: body.Message}
+	}
+	return resp, nil
+}
+
+// listAll fetches every page of a paginated endpoint
+func listAll[T any](ctx context.Context, c *Client, path string, query url.Values) ([]T, error) {
+	q := url.Values{"limit": {strconv.Itoa(pageSize)}}
+	for k, v := range query {
+		q[k] = v
+	}
+
+	var all []T
+	for page := 1; ; page++ {
+		q.Set("page", strconv.Itoa(page))
+		var items []T
+		total, err := c.get(ctx, path, q, &items)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) == 0 || (total >= 0 && len(all) >= total) || (total < 0 && len(items) < pageSize) {
+			return all, nil
+		}
+	}
+}
+
+type pullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"`
+	Draft     bool      `json:"draft"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      giteaUser `json:"user"`
+	Head      branch    `json:"head"`
+	Base      branch    `json:"base"`
+	HTMLURL   string    `json:"html_url"`
+	Labels    []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	RequestedReviewers []giteaUser `json:"requested_reviewers"`
+	RequestedTeams     []struct {
+		Name string `json:"name"`
+	} `json:"requested_reviewers_teams"`
+	Additions    *int `json:"additions"` // Missing before Gitea 1.18
+	Deletions    *int `json:"deletions"`
+	ChangedFiles *int `json:"changed_files"`
+}
+
+type giteaUser struct {
+	Login    string `json:"login"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+}
+
+type branch struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+type review struct {
+	ID          int64     `json:"id"`
+	User        giteaUser `json:"user"`
+	State       string    `json:"state"` // APPROVED, REQUEST_CHANGES, COMMENT, PENDING or REQUEST_REVIEW
+	Dismissed   bool      `json:"dismissed"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// GetPullRequests returns the open pull requests of repos of owner
+func (c *Client) GetPullRequests(ctx context.Context, owner string, repos []string) ([]*forge.PullRequest, error) {
+	var (
+		prs    []*forge.PullRequest
+		failed forge.FetchErrors
+	)
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return prs, err
+		}
+		repoPRs, err := c.getPullRequestsForRepo(ctx, owner, repo)
+		if err != nil {
+			failed = append(failed, &forge.RepoError{Owner: owner, Repo: repo, Err: err})
+			continue
+		}
+		prs = append(prs, repoPRs...)
+	}
+	if len(failed) > 0 {
+		return prs, failed
+	}
+	return prs, nil
+}
+
+func repoPath(owner, repo string) string {
+	return "repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+func (c *Client) getPullRequestsForRepo(ctx context.Context, owner, repo string) ([]*forge.PullRequest, error) {
+	open, err := listAll[pullRequest](ctx, c, repoPath(owner, repo)+"/pulls", url.Values{"state": {"open"}})
+	if err != nil {
+		return nil, err
+	}
+
+	prs := make([]*forge.PullRequest, 0, len(open))
+	for _, pr := range open {
+		converted, err := c.newPullRequest(ctx, owner, repo, pr)
+		if err != nil {
+			return nil, fmt.Errorf("PR #%d: %w", pr.Number, err)
+		}
+		prs = append(prs, converted)
+	}
+	return prs, nil
+}
+
+// GetPRDetails returns a single pull request with its reviews
+func (c *Client) GetPRDetails(ctx context.Context, owner, repo string, number int) (*forge.PullRequest, error) {
+	var pr pullRequest
+	if _, err := c.get(ctx, fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), number), nil, &pr); err != nil {
+		return nil, err
+	}
+	return c.newPullRequest(ctx, owner, repo, pr)
+}
+
+// newPullRequest maps a Gitea pull request onto the forge model, fetching
+// its reviews and, on instances that do not report them, its diff stats
+func (c *Client) newPullRequest(ctx context.Context, owner, repo string, pr pullRequest) (*forge.PullRequest, error) {
+	prPath := fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), pr.Number)
+
+	reviews, err := c.listReviews(ctx, prPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+
+	var additions, deletions, files int
+	if pr.Additions != nil && pr.Deletions != nil && pr.ChangedFiles != nil {
+		additions, deletions, files = *pr.Additions, *pr.Deletions, *pr.ChangedFiles
+	} else if additions, deletions, files, err = c.diffStats(ctx, prPath); err != nil {
+		return nil, fmt.Errorf("failed to get diff stats: %w", err)
+	}
+
+	var labels, requested []string
+	for _, label := range pr.Labels {
+		labels = append(labels, label.Name)
+	}
+	for _, user := range pr.RequestedReviewers {
+		requested = append(requested, user.Login)
+	}
+	for _, team := range pr.RequestedTeams {
+		requested = append(requested, team.Name)
+	}
+
+	result := &forge.PullRequest{
+		Number:    pr.Number,
+		Title:     pr.Title,
+		State:     pr.State,
+		Draft:     pr.Draft || isWIP(pr.Title),
+		CreatedAt: pr.CreatedAt,
+		UpdatedAt: pr.UpdatedAt,
+		User: &forge.User{
+			Login: pr.User.Login,
+			Email: pr.User.Email,
+			Name:  pr.User.FullName,
+		},
+		Head:               &forge.Branch{Ref: pr.Head.Ref, SHA: pr.Head.SHA},
+		Base:               &forge.Branch{Ref: pr.Base.Ref, SHA: pr.Base.SHA},
+		URL:                pr.HTMLURL,
+		Repo:               repo,
+		Owner:              owner,
+		Labels:             labels,
+		RequestedReviewers: requested,
+	}
+	result.SetReviews(reviews)
+	result.SetSize(additions, deletions, files)
+	return result, nil
+}
+
+// isWIP reports whether a title marks the PR as work in progress
+func isWIP(title string) bool {
+	title = strings.ToLower(strings.TrimSpace(title))
+	for _, prefix := range wipPrefixes {
+		if strings.HasPrefix(title, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// listReviews returns the submitted reviews of a PR with their states
+// mapped onto GitHub's. Pending reviews and review requests are skipped.
+func (c *Client) listReviews(ctx context.Context, prPath string) ([]forge.Review, error) {
+	submitted, err := listAll[review](ctx, c, prPath+"/reviews", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := make([]forge.Review, 0, len(submitted))
+	for _, r := range submitted {
+		state := r.State
+		switch {
+		case r.State == "PENDING" || r.State == "REQUEST_REVIEW":
+			continue
+		case r.Dismissed:
+			state = "DISMISSED"
+		case r.State == "REQUEST_CHANGES":
+			state = "CHANGES_REQUESTED"
+		case r.State == "COMMENT":
+			state = "COMMENTED"
+		}
+		reviews = append(reviews, forge.Review{ID: r.ID, User: r.User.Login, State: state, SubmittedAt: r.SubmittedAt})
+	}
+	return reviews, nil
+}
+
+// diffStats counts the added and removed lines and the changed files in the
+// unified diff of a PR
+func (c *Client) diffStats(ctx context.Context, prPath string) (additions, deletions, files int, err error) {
+	resp, err := c.do(ctx, prPath+".diff", nil, "text/plain")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	inHeader := false
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files++
+			inHeader = true
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+		case inHeader:
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	return additions, deletions, files, nil
+}
+
+type repository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Private  bool     `json:"private"`
+	Internal bool     `json:"internal"`
+	Archived bool     `json:"archived"`
+	Fork     bool     `json:"fork"`
+	Topics   []string `json:"topics"`
+}
+
+// ListRepositories lists the repositories of an organization or, if owner
+// is not one, of a user
+func (c *Client) ListRepositories(ctx context.Context, owner string) ([]*forge.Repository, error) {
+	all, err := listAll[repository](ctx, c, "orgs/"+url.PathEscape(owner)+"/repos", nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		all, err = listAll[repository](ctx, c, "users/"+url.PathEscape(owner)+"/repos", nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories of %s: %w", owner, err)
+	}
+
+	repos := make([]*forge.Repository, 0, len(all))
+	for _, r := range all {
+		visibility := "public"
+		switch {
+		case r.Private:
+			visibility = "private"
+		case r.Internal:
+			visibility = "internal"
+		}
+		repos = append(repos, &forge.Repository{
+			Owner:      r.Owner.Login,
+			Name:       r.Name,
+			Topics:     r.Topics,
+			Visibility: visibility,
+			Archived:   r.Archived,
+			Fork:       r.Fork,
+		})
+	}
+	return repos, nil
+}
+
+// Ping checks that the API is reachable and the token is accepted
+func (c *Client) Ping(ctx context.Context) error {
+	var user giteaUser
+	_, err := c.get(ctx, "user", nil, &user)
+	return err
+}
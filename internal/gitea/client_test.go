@@ -0,0 +1,84 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+)
+
+func TestClient_GetPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/infra/tools/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token gt" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Total-Count", "2")
+		w.Write([]byte(`[
+			{"number": 1, "title": "Bump deps", "state": "open", "created_at": "2024-05-01T10:00:00Z",
+			 "user": {"login": "alice", "full_name": "Alice"}, "html_url": "https://gitea.example.com/infra/tools/pulls/1",
+			 "labels": [{"name": "deps"}], "requested_reviewers": [{"login": "bob"}],
+			 "additions": 120, "deletions": 30, "changed_files": 4},
+			{"number": 2, "title": "WIP: new CLI", "state": "open", "created_at": "2024-05-02T10:00:00Z",
+			 "user": {"login": "carol"}}]`))
+	})
+	mux.HandleFunc("/api/v1/repos/infra/tools/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id": 10, "user": {"login": "bob"}, "state": "REQUEST_CHANGES", "submitted_at": "2024-05-01T12:00:00Z"},
+			{"id": 11, "user": {"login": "dave"}, "state": "APPROVED", "dismissed": true},
+			{"id": 12, "user": {"login": "erin"}, "state": "PENDING"},
+			{"id": 13, "user": {"login": "frank"}, "state": "COMMENT"}]`))
+	})
+	mux.HandleFunc("/api/v1/repos/infra/tools/pulls/2/reviews", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/api/v1/repos/infra/tools/pulls/2.diff", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n-old\n+new\n+more\n"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewClient(config.SourceConfig{BaseURL: server.URL, Token: "gt", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prs, err := client.GetPullRequests(context.Background(), "infra", []string{"tools"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("got %d PRs, want 2", len(prs))
+	}
+
+	first := prs[0]
+	if first.Draft || first.SizeCategory != "S" || first.ChangedFiles != 4 {
+		t.Errorf("PR #1 = draft %v, size %s, %d files; want ready, S, 4 files", first.Draft, first.SizeCategory, first.ChangedFiles)
+	}
+	wantStates := []string{"CHANGES_REQUESTED", "DISMISSED", "COMMENTED"}
+	if len(first.Reviews) != len(wantStates) {
+		t.Fatalf("PR #1 reviews = %+v, want states %v", first.Reviews, wantStates)
+	}
+	for i, state := range wantStates {
+		if first.Reviews[i].State != state {
+			t.Errorf("review %d state = %s, want %s", i, first.Reviews[i].State, state)
+		}
+	}
+	if first.Approved || first.ReviewCount != 2 {
+		t.Errorf("PR #1 approved = %v with %d reviews, want unapproved with 2", first.Approved, first.ReviewCount)
+	}
+	if len(first.RequestedReviewers) != 1 || len(first.Labels) != 1 {
+		t.Errorf("PR #1 requested reviewers %v, labels %v", first.RequestedReviewers, first.Labels)
+	}
+
+	second := prs[1]
+	if !second.Draft {
+		t.Error("PR #2 with a WIP prefix should be a draft")
+	}
+	if second.Additions != 2 || second.Deletions != 1 || second.ChangedFiles != 1 {
+		t.Errorf("PR #2 diff stats = +%d -%d in %d files, want +2 -1 in 1 file", second.Additions, second.Deletions, second.ChangedFiles)
+	}
+}
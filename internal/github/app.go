@@ -0,0 +1,241 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+)
+
+// tokenRefreshMargin is how long before expiry an installation token is
+// replaced, so that a token never expires in the middle of a run
+const tokenRefreshMargin = 5 * time.Minute
+
+// appAuth authenticates as a GitHub App. It signs app JWTs with the private
+// key, finds the installation of each owner and exchanges the JWT for
+// installation tokens, which it refreshes before they expire.
+type appAuth struct {
+	id     int64
+	key    *rsa.PrivateKey
+	client *github.Client // Authenticated with the app JWT
+
+	mu            sync.Mutex
+	installations map[string]int64 // Installation ID by lower-cased account login
+	tokens        map[int64]*github.InstallationToken
+}
+
+// Installation is an account the GitHub App is installed on
+type Installation struct {
+	ID      int64
+	Account string
+}
+
+func newAppAuth(cfg config.AppConfig, newClient func(http.RoundTripper) *github.Client) (*appAuth, error) {
+	key, err := parsePrivateKey([]byte(cfg.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+
+	a := &appAuth{
+		id:     cfg.ID,
+		key:    key,
+		tokens: make(map[int64]*github.InstallationToken),
+	}
+	a.client = newClient(&jwtTransport{app: a, base: http.DefaultTransport})
+	return a, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return key, nil
+}
+
+// signJWT returns an RS256 JWT identifying the app. It is backdated a minute
+// to allow for clock drift and valid for nine of the allowed ten minutes.
+func (a *appAuth) signJWT(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprint(a.id),
+	})
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(signature), nil
+}
+
+// Installations lists every account the app is installed on
+func (a *appAuth) Installations(ctx context.Context) ([]Installation, error) {
+	var installations []Installation
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := a.client.Apps.ListInstallations(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub App installations: %w", err)
+		}
+		for _, inst := range page {
+			installations = append(installations, Installation{ID: inst.GetID(), Account: inst.GetAccount().GetLogin()})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	a.mu.Lock()
+	a.installations = make(map[string]int64, len(installations))
+	for _, inst := range installations {
+		a.installations[strings.ToLower(inst.Account)] = inst.ID
+	}
+	a.mu.Unlock()
+	return installations, nil
+}
+
+// installationID returns the installation for owner, re-listing the
+// installations once if the owner is not known yet
+func (a *appAuth) installationID(ctx context.Context, owner string) (int64, error) {
+	a.mu.Lock()
+	id, ok := a.installations[strings.ToLower(owner)]
+	a.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	if _, err := a.Installations(ctx); err != nil {
+		return 0, err
+	}
+	a.mu.Lock()
+	id, ok = a.installations[strings.ToLower(owner)]
+	a.mu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("GitHub App %d is not installed on %s", a.id, owner)
+	}
+	return id, nil
+}
+
+// installationToken returns a valid token for the installation of owner,
+// creating a new one when none is cached or the cached one expires soon
+func (a *appAuth) installationToken(ctx context.Context, owner string) (string, error) {
+	id, err := a.installationID(ctx, owner)
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	token, ok := a.tokens[id]
+	a.mu.Unlock()
+	if ok && time.Until(token.GetExpiresAt().Time) > tokenRefreshMargin {
+		return token.GetToken(), nil
+	}
+
+	token, _, err = a.client.Apps.CreateInstallationToken(ctx, id, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation token for %s: %w", owner, err)
+	}
+
+	a.mu.Lock()
+	a.tokens[id] = token
+	a.mu.Unlock()
+	return token.GetToken(), nil
+}
+
+// jwtTransport authenticates app-level requests with a freshly signed JWT
+type jwtTransport struct {
+	app  *appAuth
+	base http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := t.app.signJWT(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.base.RoundTrip(req)
+}
+
+// installationTransport authenticates requests for one owner with the
+// installation token of that owner
+type installationTransport struct {
+	app   *appAuth
+	owner string
+	base  http.RoundTripper
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.app.installationToken(req.Context(), t.owner)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+token)
+	return t.base.RoundTrip(req)
+}
+
+// InstallationRepositories lists the repositories of every installation of
+// the GitHub App. It fails if the client does not authenticate as an app.
+func (c *Client) InstallationRepositories(ctx context.Context) ([]*Repository, error) {
+	if c.app == nil {
+		return nil, errors.New("no GitHub App is configured")
+	}
+
+	installations, err := c.app.Installations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []*Repository
+	for _, inst := range installations {
+		client := c.forOwner(inst.Account)
+		opts := &github.ListOptions{PerPage: 100}
+		for {
+			page, resp, err := client.Apps.ListRepos(ctx, opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list repositories of installation on %s: %w", inst.Account, err)
+			}
+			for _, r := range page.Repositories {
+				repos = append(repos, newRepository(r))
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	return repos, nil
+}
@@ -0,0 +1,104 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+)
+
+func TestGitHubApp_InstallationTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	tokensCreated := 0
+	mux := http.NewServeMux()
+	// requireJWT rejects requests without a valid app JWT. It runs on the
+	// server's goroutines, so it reports with t.Errorf rather than t.Fatalf.
+	requireJWT := func(w http.ResponseWriter, r *http.Request) bool {
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if !ok || len(parts) != 3 {
+			t.Errorf("Expected an app JWT, got %q", r.Header.Get("Authorization"))
+			http.Error(w, "app JWT required", http.StatusUnauthorized)
+			return false
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("JWT signature does not verify: %v", err)
+			http.Error(w, "invalid app JWT", http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("/api/v3/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		if !requireJWT(w, r) {
+			return
+		}
+		w.Write([]byte(`[{"id": 1, "account": {"login": "acme"}}, {"id": 2, "account": {"login": "other"}}]`))
+	})
+	mux.HandleFunc("/api/v3/app/installations/2/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		if !requireJWT(w, r) {
+			return
+		}
+		tokensCreated++
+		fmt.Fprintf(w, `{"token": "inst-2", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+	mux.HandleFunc("/api/v3/repos/other/web/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token inst-2" {
+			t.Errorf("Expected installation token, got %q", got)
+		}
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/api/v3/repos/acme/api/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer acme-pat" {
+			t.Errorf("Expected the owner's own token, got %q", got)
+		}
+		w.Write([]byte(`[]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewClientForConfig(config.GitHubConfig{
+		BaseURL: server.URL + "/api/v3/",
+		API:     "rest",
+		Token:   "pat",
+		App:     config.AppConfig{ID: 42, PrivateKey: string(keyPEM)},
+		Owners: []config.OwnerConfig{
+			{Name: "other", Repos: []string{"web"}},
+			{Name: "acme", Token: "acme-pat", Repos: []string{"api"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientForConfig failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetPullRequests(context.Background(), "other", []string{"web"}); err != nil {
+			t.Fatalf("GetPullRequests failed: %v", err)
+		}
+	}
+	// Owners with their own token keep using it
+	if _, err := client.GetPullRequests(context.Background(), "acme", []string{"api"}); err != nil {
+		t.Fatalf("GetPullRequests failed: %v", err)
+	}
+	if tokensCreated != 1 {
+		t.Errorf("Expected the installation token to be reused, created %d", tokensCreated)
+	}
+}
@@ -0,0 +1,172 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+// checkFields selects the checks of the head commit of a PR, added to
+// pullRequestFields with github.checks
+const checkFields = `
+commits(last: 1) { nodes { commit { committedDate statusCheckRollup { contexts(first: 100) { nodes {
+  __typename
+  ... on CheckRun { name status conclusion }
+  ... on StatusContext { context state }
+} } } } } }`
+
+type graphQLCommits struct {
+	Nodes []struct {
+		Commit struct {
+			CommittedDate     time.Time `json:"committedDate"`
+			StatusCheckRollup *struct {
+				Contexts struct {
+					Nodes []struct {
+						Typename   string `json:"__typename"`
+						Name       string `json:"name"`
+						Status     string `json:"status"`
+						Conclusion string `json:"conclusion"`
+						Context    string `json:"context"`
+						State      string `json:"state"`
+					} `json:"nodes"`
+				} `json:"contexts"`
+			} `json:"statusCheckRollup"`
+		} `json:"commit"`
+	} `json:"nodes"`
+}
+
+// checks converts the check runs and commit statuses of the head commit
+func (commits *graphQLCommits) checks() []Check {
+	var checks []Check
+	for _, node := range commits.Nodes {
+		if node.Commit.StatusCheckRollup == nil {
+			continue
+		}
+		for _, context := range node.Commit.StatusCheckRollup.Contexts.Nodes {
+			switch context.Typename {
+			case "CheckRun":
+				checks = append(checks, Check{Name: context.Name, State: checkRunState(context.Status, context.Conclusion)})
+			case "StatusContext":
+				checks = append(checks, Check{Name: context.Context, State: statusState(context.State)})
+			}
+		}
+	}
+	return checks
+}
+
+// committed returns when the head commit was made
+func (commits *graphQLCommits) committed() time.Time {
+	var committed time.Time
+	for _, node := range commits.Nodes {
+		committed = node.Commit.CommittedDate
+	}
+	return committed
+}
+
+// addChecks fetches the commit statuses and check runs of the head commit
+// of pr over REST, if github.checks is enabled
+func (c *Client) addChecks(ctx context.Context, pr *PullRequest) error {
+	if !c.checks.Enabled || pr.Head == nil || pr.Head.SHA == "" {
+		return nil
+	}
+	client := c.forOwner(pr.Owner)
+
+	var checks []Check
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		combined, resp, err := client.Repositories.GetCombinedStatus(ctx, pr.Owner, pr.Repo, pr.Head.SHA, opts)
+		if err != nil {
+			return fmt.Errorf("failed to get commit statuses: %w", err)
+		}
+		for _, status := range combined.Statuses {
+			checks = append(checks, Check{Name: status.GetContext(), State: statusState(status.GetState())})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	runOpts := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		runs, resp, err := client.Checks.ListCheckRunsForRef(ctx, pr.Owner, pr.Repo, pr.Head.SHA, runOpts)
+		if err != nil {
+			return fmt.Errorf("failed to list check runs: %w", err)
+		}
+		for _, run := range runs.CheckRuns {
+			checks = append(checks, Check{Name: run.GetName(), State: checkRunState(run.GetStatus(), run.GetConclusion())})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		runOpts.Page = resp.NextPage
+	}
+
+	// Only a required check that has not reported needs the commit date
+	var committed time.Time
+	for _, name := range c.checks.RequiredFor(pr.Owner, pr.Repo) {
+		if !slices.ContainsFunc(checks, func(check Check) bool { return strings.EqualFold(name, check.Name) }) {
+			commit, _, err := client.Git.GetCommit(ctx, pr.Owner, pr.Repo, pr.Head.SHA)
+			if err != nil {
+				return fmt.Errorf("failed to get the head commit: %w", err)
+			}
+			committed = commit.GetCommitter().GetDate().Time
+			break
+		}
+	}
+
+	pr.Checks = c.markRequired(pr, checks, committed)
+	return nil
+}
+
+// markRequired flags the checks required for the repository of pr, and
+// adds a pending one for each of those that has not reported on the head
+// commit made at committed. Names are matched ignoring case.
+func (c *Client) markRequired(pr *PullRequest, checks []Check, committed time.Time) []Check {
+	required := c.checks.RequiredFor(pr.Owner, pr.Repo)
+	for i := range checks {
+		checks[i].Required = slices.ContainsFunc(required, func(name string) bool { return strings.EqualFold(name, checks[i].Name) })
+		if checks[i].Required {
+			checks[i].Timeout = c.checks.MissingTimeout
+		}
+	}
+	if committed.IsZero() {
+		committed = pr.CreatedAt
+	}
+	for _, name := range required {
+		if !slices.ContainsFunc(checks, func(check Check) bool { return strings.EqualFold(name, check.Name) }) {
+			checks = append(checks, Check{Name: name, State: forge.CIPending, Required: true, Since: committed, Timeout: c.checks.MissingTimeout})
+		}
+	}
+	return checks
+}
+
+// checkRunState maps the status and conclusion of a check run to a CI state
+func checkRunState(status, conclusion string) string {
+	if !strings.EqualFold(status, "completed") {
+		return forge.CIPending
+	}
+	switch strings.ToLower(conclusion) {
+	case "success", "neutral", "skipped":
+		return forge.CIPassing
+	default:
+		return forge.CIFailing
+	}
+}
+
+// statusState maps the state of a commit status to a CI state
+func statusState(state string) string {
+	switch strings.ToLower(state) {
+	case "success":
+		return forge.CIPassing
+	case "pending", "expected":
+		return forge.CIPending
+	default:
+		return forge.CIFailing
+	}
+}
@@ -3,105 +3,248 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v60/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
 	"golang.org/x/oauth2"
 )
 
 // Client wraps the GitHub client with additional functionality
 type Client struct {
-	client *github.Client
-	ctx    context.Context
+	client *github.Client // For github.token; nil when only a GitHub App or owner tokens are configured
+	app    *appAuth       // GitHub App authentication, if configured
+
+	mu        sync.Mutex
+	owners    map[string]*github.Client // Owners with their own token or installation, keyed by lower-cased login
+	bots      map[*github.Client]bool   // Installation clients, which act as the app's bot user
+	logins    map[*github.Client]string // User each client is authenticated as, looked up on first use
+	newClient func(http.RoundTripper) *github.Client
+	api       string // auto, graphql or rest; empty means rest
+
+	concurrency int                 // Repositories or GraphQL batches fetched in parallel
+	checks      config.ChecksConfig // Whether to fetch the checks of head commits
 }
 
-// PullRequest represents a GitHub pull request with additional metadata
-type PullRequest struct {
-	Number       int       `json:"number"`
-	Title        string    `json:"title"`
-	State        string    `json:"state"`
-	Draft        bool      `json:"draft"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	User         *User     `json:"user"`
-	Head         *Branch   `json:"head"`
-	Base         *Branch   `json:"base"`
-	URL          string    `json:"html_url"`
-	Approved     bool      `json:"approved"`
-	ReviewCount  int       `json:"review_count"`
-	Repo         string    `json:"repo"`
-	Additions    int       `json:"additions"`
-	Deletions    int       `json:"deletions"`
-	TotalChanges int       `json:"total_changes"`
-	ChangedFiles int       `json:"changed_files"`
-	SizeCategory string    `json:"size_category"` // XS, S, M, L, XL
+// The pull request model is shared by every forge
+type (
+	PullRequest   = forge.PullRequest
+	Review        = forge.Review
+	TimelineEvent = forge.TimelineEvent
+	Activity      = forge.Activity
+	Check         = forge.Check
+	User          = forge.User
+	Branch        = forge.Branch
+	Repository    = forge.Repository
+	RepoError     = forge.RepoError
+	FetchErrors   = forge.FetchErrors
+)
+
+// NewClient creates a new GitHub client
+func NewClient(token string) (*Client, error) {
+	if token == "" {
+		return nil, fmt.Errorf("GitHub token is required")
+	}
+
+	return &Client{
+		client: newGitHubClient(tokenTransport(token), config.RateLimitConfig{}, 0),
+	}, nil
 }
 
-// User represents a GitHub user
-type User struct {
-	Login string `json:"login"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
+// NewClientForConfig creates a client for every owner in the configuration.
+// Owners with their own token get a dedicated API client. With a GitHub App
+// configured, the other owners are accessed through the app's installation
+// on them, even when github.token is set; otherwise they share the client
+// for github.token. GitHub Enterprise URLs apply to all of them.
+func NewClientForConfig(cfg config.GitHubConfig) (*Client, error) {
+	uploadURL := cfg.UploadURL
+	if uploadURL == "" {
+		uploadURL = cfg.BaseURL
+	}
+	if cfg.BaseURL != "" {
+		if _, err := github.NewClient(nil).WithEnterpriseURLs(cfg.BaseURL, uploadURL); err != nil {
+			return nil, fmt.Errorf("invalid GitHub URL: %w", err)
+		}
+	}
+	newClient := func(base http.RoundTripper) *github.Client {
+		client := newGitHubClient(base, cfg.RateLimit, cfg.Timeout)
+		if cfg.BaseURL == "" {
+			return client
+		}
+		// The URLs were checked above
+		client, _ = client.WithEnterpriseURLs(cfg.BaseURL, uploadURL)
+		return client
+	}
+
+	c := &Client{
+		owners:      make(map[string]*github.Client),
+		newClient:   newClient,
+		api:         cfg.API,
+		concurrency: cfg.Concurrency,
+		checks:      cfg.Checks,
+	}
+	if cfg.App.ID != 0 {
+		app, err := newAppAuth(cfg.App, newClient)
+		if err != nil {
+			return nil, err
+		}
+		c.app = app
+	}
+	if cfg.Token != "" {
+		c.client = newClient(tokenTransport(cfg.Token))
+	}
+
+	targets := cfg.Targets()
+	for _, target := range targets {
+		if target.Token == "" {
+			if c.app != nil {
+				continue
+			}
+			return nil, fmt.Errorf("GitHub token is required for owner %s", target.Owner)
+		}
+		if target.Token == cfg.Token {
+			// Owners without their own token go through the app's
+			// installation when there is one, and share the client for
+			// github.token otherwise
+			continue
+		}
+		c.owners[strings.ToLower(target.Owner)] = newClient(tokenTransport(target.Token))
+	}
+
+	if c.client == nil && c.app == nil {
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("GitHub token is required")
+		}
+		// Every owner has its own token; requests for owners outside the
+		// configuration use the first one
+		c.client = c.owners[strings.ToLower(targets[0].Owner)]
+	}
+	return c, nil
 }
 
-// Branch represents a Git branch
-type Branch struct {
-	Ref string `json:"ref"`
-	SHA string `json:"sha"`
+// newGitHubClient creates an API client on top of an authenticating
+// transport, with requests paced by a rateLimitTransport. Each token or
+// installation has its own rate limit and cache. timeout bounds every
+// attempt of a request, not the waits between them.
+func newGitHubClient(base http.RoundTripper, rateLimit config.RateLimitConfig, timeout time.Duration) *github.Client {
+	return github.NewClient(&http.Client{Transport: newRateLimitTransport(base, rateLimit, timeout)})
 }
 
-func categorizePRSize(totalChanges int) string {
-	switch {
-	case totalChanges <= 50:
-		return "XS"
-	case totalChanges <= 200:
-		return "S"
-	case totalChanges <= 500:
-		return "M"
-	case totalChanges <= 1000:
-		return "L"
-	default:
-		return "XL"
+// tokenTransport authenticates requests with a personal access token
+func tokenTransport(token string) http.RoundTripper {
+	return &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})}
+}
+
+// forOwner returns the API client authenticated for owner. With a GitHub
+// App, clients for the installations are created on first use.
+func (c *Client) forOwner(owner string) *github.Client {
+	key := strings.ToLower(owner)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.owners[key]; ok {
+		return client
+	}
+	if c.app == nil {
+		return c.client
+	}
+	client := c.newClient(&installationTransport{app: c.app, owner: owner, base: http.DefaultTransport})
+	c.owners[key] = client
+	if c.bots == nil {
+		c.bots = make(map[*github.Client]bool)
 	}
+	c.bots[client] = true
+	return client
 }
 
-// NewClient creates a new GitHub client
-func NewClient(token string) (*Client, error) {
-	if token == "" {
-		return nil, fmt.Errorf("GitHub token is required")
+// GetPullRequests fetches all open pull requests for the given repositories,
+// up to github.concurrency repositories (or GraphQL batches) at a time.
+// Depending on github.api they are fetched in bulk over GraphQL, falling back
+// to REST if a GraphQL query fails, or over REST only. Repositories that
+// fail are reported as FetchErrors while the others are still returned.
+func (c *Client) GetPullRequests(ctx context.Context, owner string, repos []string) ([]*PullRequest, error) {
+	useGraphQL := c.api == "graphql" || c.api == "auto"
+
+	// Each unit is fetched by one goroutine: a GraphQL batch or a single
+	// repository over REST
+	var units [][]string
+	size := 1
+	if useGraphQL {
+		size = graphQLBatchSize
+	}
+	for start := 0; start < len(repos); start += size {
+		units = append(units, repos[start:min(start+size, len(repos))])
 	}
 
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+	concurrency := c.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
 
-	client := github.NewClient(tc)
+	results := make([][]*PullRequest, len(units))
+	failures := make([]FetchErrors, len(units))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, unit := range units {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := ctx.Err(); err != nil {
+				for _, repo := range unit {
+					failures[i] = append(failures[i], &RepoError{Owner: owner, Repo: repo, Err: err})
+				}
+				return
+			}
 
-	return &Client{
-		client: client,
-		ctx:    ctx,
-	}, nil
-}
+			if useGraphQL {
+				prs, repoErrs, err := c.getPullRequestsGraphQL(ctx, owner, unit)
+				if err == nil {
+					results[i], failures[i] = prs, repoErrs
+					return
+				}
+				if c.api == "graphql" {
+					for _, repo := range unit {
+						failures[i] = append(failures[i], &RepoError{Owner: owner, Repo: repo, Err: err})
+					}
+					return
+				}
+				logger.Error("GraphQL fetch for %s failed, falling back to REST: %v", owner, err)
+			}
 
-// GetPullRequests fetches all open pull requests for the given repositories
-func (c *Client) GetPullRequests(owner string, repos []string) ([]*PullRequest, error) {
-	var allPRs []*PullRequest
+			for _, repo := range unit {
+				prs, err := c.getPullRequestsForRepo(ctx, owner, repo)
+				if err != nil {
+					failures[i] = append(failures[i], &RepoError{Owner: owner, Repo: repo, Err: err})
+					continue
+				}
+				results[i] = append(results[i], prs...)
+			}
+		}()
+	}
+	wg.Wait()
 
-	for _, repo := range repos {
-		prs, err := c.getPullRequestsForRepo(owner, repo)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get PRs for repo %s: %w", repo, err)
-		}
-		allPRs = append(allPRs, prs...)
+	var allPRs []*PullRequest
+	var failed FetchErrors
+	for i := range units {
+		allPRs = append(allPRs, results[i]...)
+		failed = append(failed, failures[i]...)
 	}
 
+	if len(failed) > 0 {
+		return allPRs, failed
+	}
 	return allPRs, nil
 }
 
 // getPullRequestsForRepo fetches pull requests for a specific repository
-func (c *Client) getPullRequestsForRepo(owner, repo string) ([]*PullRequest, error) {
+func (c *Client) getPullRequestsForRepo(ctx context.Context, owner, repo string) ([]*PullRequest, error) {
 	var prs []*PullRequest
 
 	opts := &github.PullRequestListOptions{
@@ -112,56 +255,25 @@ func (c *Client) getPullRequestsForRepo(owner, repo string) ([]*PullRequest, err
 	}
 
 	for {
-		githubPRs, resp, err := c.client.PullRequests.List(c.ctx, owner, repo, opts)
+		githubPRs, resp, err := c.forOwner(owner).PullRequests.List(ctx, owner, repo, opts)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, pr := range githubPRs {
-
-			// Check if PR has approvals
-			approved, reviewCount, err := c.checkPRApprovals(owner, repo, pr.GetNumber())
+			reviews, err := c.listReviews(ctx, owner, repo, pr.GetNumber())
 			if err != nil {
 				// Log error but continue processing
-				fmt.Printf("Warning: failed to check approvals for PR #%d: %v\n", pr.GetNumber(), err)
+				logger.With(logger.FieldRepo, owner+"/"+repo, logger.FieldPR, pr.GetNumber()).
+					Error("Warning: failed to check approvals for PR #%d: %v", pr.GetNumber(), err)
 			}
 
-			additions := pr.GetAdditions()
-			deletions := pr.GetDeletions()
-			totalChanges := additions + deletions
-
-			prData := &PullRequest{
-				Number:    pr.GetNumber(),
-				Title:     pr.GetTitle(),
-				State:     pr.GetState(),
-				Draft:     pr.GetDraft(),
-				CreatedAt: pr.GetCreatedAt().Time,
-				UpdatedAt: pr.GetUpdatedAt().Time,
-				User: &User{
-					Login: pr.User.GetLogin(),
-					Email: pr.User.GetEmail(),
-					Name:  pr.User.GetName(),
-				},
-				Head: &Branch{
-					Ref: pr.Head.GetRef(),
-					SHA: pr.Head.GetSHA(),
-				},
-				Base: &Branch{
-					Ref: pr.Base.GetRef(),
-					SHA: pr.Base.GetSHA(),
-				},
-				URL:          pr.GetHTMLURL(),
-				Approved:     approved,
-				ReviewCount:  reviewCount,
-				Repo:         repo,
-				Additions:    additions,
-				Deletions:    deletions,
-				TotalChanges: totalChanges,
-				ChangedFiles: pr.GetChangedFiles(),
-				SizeCategory: categorizePRSize(totalChanges),
+			converted := newPullRequest(owner, repo, pr, reviews)
+			if err := c.addChecks(ctx, converted); err != nil {
+				logger.With(logger.FieldRepo, owner+"/"+repo, logger.FieldPR, pr.GetNumber()).
+					Error("Warning: failed to fetch the checks of PR #%d: %v", pr.GetNumber(), err)
 			}
-
-			prs = append(prs, prData)
+			prs = append(prs, converted)
 		}
 
 		if resp.NextPage == 0 {
@@ -173,48 +285,25 @@ func (c *Client) getPullRequestsForRepo(owner, repo string) ([]*PullRequest, err
 	return prs, nil
 }
 
-// checkPRApprovals checks if a PR has been approved
-func (c *Client) checkPRApprovals(owner, repo string, prNumber int) (bool, int, error) {
-	opts := &github.ListOptions{
-		PerPage: 100,
-	}
-
-	reviews, _, err := c.client.PullRequests.ListReviews(c.ctx, owner, repo, prNumber, opts)
-	if err != nil {
-		return false, 0, err
-	}
-
-	approved := false
-	reviewCount := 0
+// newPullRequest converts a REST pull request and its reviews
+func newPullRequest(owner, repo string, pr *github.PullRequest, reviews []Review) *PullRequest {
+	additions := pr.GetAdditions()
+	deletions := pr.GetDeletions()
+	totalChanges := additions + deletions
+	approved, reviewCount := forge.SummarizeReviews(reviews)
 
-	for _, review := range reviews {
-		if review.GetState() == "APPROVED" {
-			approved = true
-		}
-		if review.GetState() != "COMMENTED" {
-			reviewCount++
-		}
+	var labels []string
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
 	}
-
-	return approved, reviewCount, nil
-}
-
-// GetPRDetails fetches detailed information about a specific PR
-func (c *Client) GetPRDetails(owner, repo string, prNumber int) (*PullRequest, error) {
-	pr, _, err := c.client.PullRequests.Get(c.ctx, owner, repo, prNumber)
-	if err != nil {
-		return nil, err
+	var requested []string
+	for _, user := range pr.RequestedReviewers {
+		requested = append(requested, user.GetLogin())
 	}
-
-	approved, reviewCount, err := c.checkPRApprovals(owner, repo, prNumber)
-	if err != nil {
-		return nil, err
+	for _, team := range pr.RequestedTeams {
+		requested = append(requested, team.GetSlug())
 	}
 
-	additions := pr.GetAdditions()
-	deletions := pr.GetDeletions()
-	totalChanges := additions + deletions
-
 	return &PullRequest{
 		Number:    pr.GetNumber(),
 		Title:     pr.GetTitle(),
@@ -235,14 +324,96 @@ func (c *Client) GetPRDetails(owner, repo string, prNumber int) (*PullRequest, e
 			Ref: pr.Base.GetRef(),
 			SHA: pr.Base.GetSHA(),
 		},
-		URL:          pr.GetHTMLURL(),
-		Approved:     approved,
-		ReviewCount:  reviewCount,
-		Repo:         repo,
-		Additions:    additions,
-		Deletions:    deletions,
-		TotalChanges: totalChanges,
-		ChangedFiles: pr.GetChangedFiles(),
-		SizeCategory: categorizePRSize(totalChanges),
-	}, nil
+		URL:                pr.GetHTMLURL(),
+		Approved:           approved,
+		ReviewCount:        reviewCount,
+		Repo:               repo,
+		Owner:              owner,
+		Additions:          additions,
+		Deletions:          deletions,
+		TotalChanges:       totalChanges,
+		ChangedFiles:       pr.GetChangedFiles(),
+		SizeCategory:       forge.SizeCategory(totalChanges),
+		Labels:             labels,
+		RequestedReviewers: requested,
+		Reviews:            reviews,
+	}
+}
+
+// listReviews lists the submitted reviews of a PR
+func (c *Client) listReviews(ctx context.Context, owner, repo string, prNumber int) ([]Review, error) {
+	opts := &github.ListOptions{
+		PerPage: 100,
+	}
+
+	githubReviews, _, err := c.forOwner(owner).PullRequests.ListReviews(ctx, owner, repo, prNumber, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := make([]Review, 0, len(githubReviews))
+	for _, review := range githubReviews {
+		reviews = append(reviews, Review{
+			ID:          review.GetID(),
+			User:        review.GetUser().GetLogin(),
+			State:       review.GetState(),
+			SubmittedAt: review.GetSubmittedAt().Time,
+		})
+	}
+	return reviews, nil
+}
+
+// GetPRDetails fetches detailed information about a specific PR
+func (c *Client) GetPRDetails(ctx context.Context, owner, repo string, prNumber int) (*PullRequest, error) {
+	pr, _, err := c.forOwner(owner).PullRequests.Get(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := c.listReviews(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := newPullRequest(owner, repo, pr, reviews)
+	if err := c.addChecks(ctx, converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// ClosePullRequest closes a pull request without merging it
+func (c *Client) ClosePullRequest(ctx context.Context, owner, repo string, number int) error {
+	state := "closed"
+	_, _, err := c.forOwner(owner).PullRequests.Edit(ctx, owner, repo, number, &github.PullRequest{State: &state})
+	return err
+}
+
+// Ping checks that the GitHub API is reachable and every token is accepted.
+// It queries the rate limit endpoint, which does not count against the
+// quota, or the app itself when authenticating as a GitHub App.
+func (c *Client) Ping(ctx context.Context) error {
+	if c.client != nil {
+		if _, _, err := c.client.RateLimit.Get(ctx); err != nil {
+			return err
+		}
+	}
+	if c.app != nil {
+		if _, _, err := c.app.client.Apps.Get(ctx, ""); err != nil {
+			return fmt.Errorf("GitHub App: %w", err)
+		}
+	}
+
+	c.mu.Lock()
+	owners := make(map[string]*github.Client, len(c.owners))
+	for owner, client := range c.owners {
+		owners[owner] = client
+	}
+	c.mu.Unlock()
+	for owner, client := range owners {
+		if _, _, err := client.RateLimit.Get(ctx); err != nil {
+			return fmt.Errorf("token for %s: %w", owner, err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,42 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v60/github"
+)
+
+func TestGetPullRequests_CollectsRepoErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/acme/api/pulls", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"number": 1, "title": "Fix", "state": "open", "user": {"login": "alice"}}]`))
+	})
+	mux.HandleFunc("/api/v3/repos/acme/api/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"state": "APPROVED", "user": {"login": "bob"}}]`))
+	})
+	mux.HandleFunc("/api/v3/repos/acme/renamed/pulls", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	gh, err := github.NewClient(nil).WithEnterpriseURLs(server.URL+"/api/v3/", server.URL+"/api/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{client: gh, api: "rest", concurrency: 2}
+
+	prs, err := client.GetPullRequests(context.Background(), "acme", []string{"api", "renamed"})
+
+	var failed FetchErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || failed[0].Repo != "renamed" {
+		t.Fatalf("Expected a fetch error for acme/renamed only, got %v", err)
+	}
+	if len(prs) != 1 || prs[0].Number != 1 || !prs[0].Approved {
+		t.Errorf("Expected the approved PR of acme/api, got %+v", prs)
+	}
+}
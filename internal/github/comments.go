@@ -0,0 +1,85 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// UpsertComment edits the conversation comment of a pull request that
+// contains marker and was written by the authenticated user or app, or
+// creates one if there is none. The comment is left alone when its body is
+// already up to date.
+func (c *Client) UpsertComment(ctx context.Context, owner, repo string, number int, marker, body string) error {
+	client := c.forOwner(owner)
+	login, err := c.login(ctx, client)
+	if err != nil {
+		return err
+	}
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		comments, resp, err := client.Issues.ListComments(ctx, owner, repo, number, opts)
+		if err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			if !strings.EqualFold(comment.GetUser().GetLogin(), login) || !strings.Contains(comment.GetBody(), marker) {
+				continue
+			}
+			if comment.GetBody() == body {
+				return nil
+			}
+			_, _, err := client.Issues.EditComment(ctx, owner, repo, comment.GetID(), &github.IssueComment{Body: &body})
+			return err
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	_, _, err = client.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: &body})
+	return err
+}
+
+// login returns the login of the user client is authenticated as. For a
+// GitHub App installation that is the app's bot user, named after its slug.
+func (c *Client) login(ctx context.Context, client *github.Client) (string, error) {
+	c.mu.Lock()
+	login, ok := c.logins[client]
+	bot := c.bots[client]
+	c.mu.Unlock()
+	if ok {
+		return login, nil
+	}
+
+	if bot {
+		app, _, err := c.app.client.Apps.Get(ctx, "")
+		if err != nil {
+			return "", fmt.Errorf("failed to get the GitHub App: %w", err)
+		}
+		login = app.GetSlug() + "[bot]"
+	} else {
+		user, _, err := client.Users.Get(ctx, "")
+		if err != nil {
+			return "", fmt.Errorf("failed to get the authenticated user: %w", err)
+		}
+		login = user.GetLogin()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.logins == nil {
+		c.logins = make(map[*github.Client]string)
+	}
+	c.logins[client] = login
+	return login, nil
+}
@@ -0,0 +1,84 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v60/github"
+)
+
+func TestUpsertComment(t *testing.T) {
+	const marker = "<!-- marker -->"
+	var created, edited []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"login": "watcher"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/acme/api/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var c github.IssueComment
+			json.NewDecoder(r.Body).Decode(&c)
+			created = append(created, c.GetBody())
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 99}`))
+			return
+		}
+		w.Write([]byte(`[{"id": 10, "body": "LGTM", "user": {"login": "bob"}},
+			{"id": 12, "body": "Quoting: ` + marker + `", "user": {"login": "mallory"}},
+			{"id": 11, "body": "` + marker + `\nold reminder", "user": {"login": "Watcher"}}]`))
+	})
+	mux.HandleFunc("/api/v3/repos/acme/api/issues/2/comments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var c github.IssueComment
+			json.NewDecoder(r.Body).Decode(&c)
+			created = append(created, c.GetBody())
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 100}`))
+			return
+		}
+		w.Write([]byte(`[{"id": 20, "body": "` + marker + `\nnot ours", "user": {"login": "mallory"}}]`))
+	})
+	mux.HandleFunc("/api/v3/repos/acme/api/issues/comments/11", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("method = %s, want PATCH", r.Method)
+		}
+		var c github.IssueComment
+		json.NewDecoder(r.Body).Decode(&c)
+		edited = append(edited, c.GetBody())
+		w.Write([]byte(`{"id": 11}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	gh, err := github.NewClient(nil).WithEnterpriseURLs(server.URL+"/api/v3/", server.URL+"/api/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{client: gh}
+
+	if err := client.UpsertComment(context.Background(), "acme", "api", 1, marker, marker+"\nnew reminder"); err != nil {
+		t.Fatal(err)
+	}
+	if len(edited) != 1 || len(created) != 0 {
+		t.Fatalf("expected the marked comment to be edited, got %d edits and %d new comments", len(edited), len(created))
+	}
+
+	// An unchanged body is not written again
+	if err := client.UpsertComment(context.Background(), "acme", "api", 1, marker, marker+"\nold reminder"); err != nil {
+		t.Fatal(err)
+	}
+	if len(edited) != 1 || len(created) != 0 {
+		t.Fatalf("expected an up to date comment to be left alone, got %d edits and %d new comments", len(edited), len(created))
+	}
+
+	if err := client.UpsertComment(context.Background(), "acme", "api", 2, marker, marker+"\nfirst reminder"); err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 || created[0] != marker+"\nfirst reminder" {
+		t.Errorf("expected a new comment on a PR with only someone else's marker, got %q", created)
+	}
+}
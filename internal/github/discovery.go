@@ -0,0 +1,203 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+)
+
+// ListRepositories lists every repository of owner, which may be an
+// organization or a user. For the authenticated user private repositories
+// are included; for other users only public ones are visible.
+func (c *Client) ListRepositories(ctx context.Context, owner string) ([]*Repository, error) {
+	client := c.forOwner(owner)
+	account, _, err := client.Users.Get(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up owner %s: %w", owner, err)
+	}
+
+	var list func(page int) ([]*github.Repository, *github.Response, error)
+	switch {
+	case account.GetType() == "Organization":
+		list = func(page int) ([]*github.Repository, *github.Response, error) {
+			return client.Repositories.ListByOrg(ctx, owner, &github.RepositoryListByOrgOptions{
+				Type:        "all",
+				ListOptions: github.ListOptions{PerPage: 100, Page: page},
+			})
+		}
+	case c.isAuthenticatedUser(ctx, owner):
+		list = func(page int) ([]*github.Repository, *github.Response, error) {
+			return client.Repositories.ListByAuthenticatedUser(ctx, &github.RepositoryListByAuthenticatedUserOptions{
+				Affiliation: "owner",
+				ListOptions: github.ListOptions{PerPage: 100, Page: page},
+			})
+		}
+	default:
+		list = func(page int) ([]*github.Repository, *github.Response, error) {
+			return client.Repositories.ListByUser(ctx, owner, &github.RepositoryListByUserOptions{
+				Type:        "owner",
+				ListOptions: github.ListOptions{PerPage: 100, Page: page},
+			})
+		}
+	}
+
+	var repos []*Repository
+	page := 0
+	for {
+		githubRepos, resp, err := list(page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories of %s: %w", owner, err)
+		}
+
+		for _, r := range githubRepos {
+			repos = append(repos, newRepository(r))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	return repos, nil
+}
+
+func newRepository(r *github.Repository) *Repository {
+	visibility := r.GetVisibility()
+	if visibility == "" {
+		visibility = "public"
+		if r.GetPrivate() {
+			visibility = "private"
+		}
+	}
+	return &Repository{
+		Owner:      r.GetOwner().GetLogin(),
+		Name:       r.GetName(),
+		Topics:     r.Topics,
+		Visibility: visibility,
+		Archived:   r.GetArchived(),
+		Fork:       r.GetFork(),
+	}
+}
+
+func (c *Client) isAuthenticatedUser(ctx context.Context, login string) bool {
+	user, _, err := c.forOwner(login).Users.Get(ctx, "")
+	return err == nil && strings.EqualFold(user.GetLogin(), login)
+}
+
+// RepoFilter selects discovered repositories according to the discovery
+// section of the configuration
+type RepoFilter struct {
+	include         []matcher
+	exclude         []matcher
+	topics          []string
+	visibility      string
+	includeArchived bool
+	includeForks    bool
+}
+
+// matcher matches repository names against a glob, or a regular expression
+// when the pattern is wrapped in slashes ("/^svc-.*$/")
+type matcher func(name string) bool
+
+func NewRepoFilter(cfg config.DiscoveryConfig) (*RepoFilter, error) {
+	f := &RepoFilter{
+		topics:          cfg.Topics,
+		visibility:      strings.ToLower(cfg.Visibility),
+		includeArchived: cfg.IncludeArchived,
+		includeForks:    cfg.IncludeForks,
+	}
+
+	var err error
+	if f.include, err = compilePatterns(cfg.Include); err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	if f.exclude, err = compilePatterns(cfg.Exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	return f, nil
+}
+
+func compilePatterns(patterns []string) ([]matcher, error) {
+	matchers := make([]matcher, 0, len(patterns))
+	for _, p := range patterns {
+		if len(p) >= 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			re, err := regexp.Compile(p[1 : len(p)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", p, err)
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		pattern := p
+		matchers = append(matchers, func(name string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		})
+	}
+	return matchers, nil
+}
+
+// Match reports whether repo passes every configured filter
+func (f *RepoFilter) Match(repo *Repository) bool {
+	if repo.Archived && !f.includeArchived {
+		return false
+	}
+	if repo.Fork && !f.includeForks {
+		return false
+	}
+	if f.visibility != "" && f.visibility != "all" && repo.Visibility != f.visibility {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, repo.Name) {
+		return false
+	}
+	if matchAny(f.exclude, repo.Name) {
+		return false
+	}
+	if len(f.topics) > 0 && !hasAnyTopic(repo.Topics, f.topics) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the sorted names of the repositories that match
+func (f *RepoFilter) Filter(repos []*Repository) []string {
+	var names []string
+	for _, repo := range repos {
+		if f.Match(repo) {
+			names = append(names, repo.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func matchAny(matchers []matcher, name string) bool {
+	for _, m := range matchers {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyTopic(topics, wanted []string) bool {
+	for _, t := range topics {
+		for _, w := range wanted {
+			if strings.EqualFold(t, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+)
+
+func TestRepoFilter_Filter(t *testing.T) {
+	repos := []*Repository{
+		{Name: "api", Visibility: "private", Topics: []string{"backend"}},
+		{Name: "api-legacy", Visibility: "private", Archived: true},
+		{Name: "web", Visibility: "public", Topics: []string{"frontend"}},
+		{Name: "svc-billing", Visibility: "internal", Topics: []string{"backend"}},
+		{Name: "svc-sandbox", Visibility: "internal", Topics: []string{"backend"}},
+		{Name: "fork-of-lib", Visibility: "public", Fork: true},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.DiscoveryConfig
+		expected []string
+	}{
+		{
+			name:     "defaults skip archived and forks",
+			cfg:      config.DiscoveryConfig{},
+			expected: []string{"api", "svc-billing", "svc-sandbox", "web"},
+		},
+		{
+			name:     "glob include with exclude",
+			cfg:      config.DiscoveryConfig{Include: []string{"svc-*"}, Exclude: []string{"*-sandbox"}},
+			expected: []string{"svc-billing"},
+		},
+		{
+			name:     "regex include",
+			cfg:      config.DiscoveryConfig{Include: []string{"/^(api|web)$/"}},
+			expected: []string{"api", "web"},
+		},
+		{
+			name:     "topics and visibility",
+			cfg:      config.DiscoveryConfig{Topics: []string{"backend"}, Visibility: "internal"},
+			expected: []string{"svc-billing", "svc-sandbox"},
+		},
+		{
+			name:     "archived and forks included on request",
+			cfg:      config.DiscoveryConfig{Include: []string{"api*", "fork-*"}, IncludeArchived: true, IncludeForks: true},
+			expected: []string{"api", "api-legacy", "fork-of-lib"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewRepoFilter(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewRepoFilter failed: %v", err)
+			}
+			if got := filter.Filter(repos); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+const (
+	// graphQLBatchSize is the number of repositories queried per request
+	graphQLBatchSize = 5
+	// graphQLPageSize is the number of PRs per repository and page. The
+	// nested reviews and timeline make larger pages expensive.
+	graphQLPageSize = 25
+)
+
+// pullRequestFields selects everything the watcher needs about a PR in one
+// round trip, instead of a list call plus one reviews call per PR over REST
+const pullRequestFields = `
+number title state isDraft createdAt updatedAt url
+additions deletions changedFiles
+author { login ... on User { name email } }
+headRefName headRefOid baseRefName baseRefOid
+labels(first: 50) { nodes { name } }
+reviewRequests(first: 50) { nodes { requestedReviewer { ... on User { login } ... on Team { slug } ... on Mannequin { login } } } }
+reviews(first: 100) { nodes { databaseId state submittedAt author { login } } }
+timelineItems(last: 50, itemTypes: [PULL_REQUEST_COMMIT, HEAD_REF_FORCE_PUSHED_EVENT, ISSUE_COMMENT, PULL_REQUEST_REVIEW, READY_FOR_REVIEW_EVENT, REVIEW_REQUESTED_EVENT]) {
+  nodes {
+    __typename
+    ... on PullRequestCommit { commit { committedDate author { user { login } } } }
+    ... on HeadRefForcePushedEvent { createdAt actor { login } }
+    ... on IssueComment { createdAt body author { login } }
+    ... on PullRequestReview { submittedAt author { login } }
+    ... on ReadyForReviewEvent { createdAt actor { login } }
+    ... on ReviewRequestedEvent { createdAt actor { login } }
+  }
+}`
+
+type graphQLLogin struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Slug  string `json:"slug"`
+}
+
+type graphQLPullRequest struct {
+	Number       int           `json:"number"`
+	Title        string        `json:"title"`
+	State        string        `json:"state"`
+	IsDraft      bool          `json:"isDraft"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	URL          string        `json:"url"`
+	Additions    int           `json:"additions"`
+	Deletions    int           `json:"deletions"`
+	ChangedFiles int           `json:"changedFiles"`
+	Author       *graphQLLogin `json:"author"`
+	HeadRefName  string        `json:"headRefName"`
+	HeadRefOid   string        `json:"headRefOid"`
+	BaseRefName  string        `json:"baseRefName"`
+	BaseRefOid   string        `json:"baseRefOid"`
+	Labels       struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer *graphQLLogin `json:"requestedReviewer"`
+		} `json:"nodes"`
+	} `json:"reviewRequests"`
+	Reviews struct {
+		Nodes []struct {
+			DatabaseID  int64         `json:"databaseId"`
+			State       string        `json:"state"`
+			SubmittedAt time.Time     `json:"submittedAt"`
+			Author      *graphQLLogin `json:"author"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+	TimelineItems struct {
+		Nodes []graphQLTimelineItem `json:"nodes"`
+	} `json:"timelineItems"`
+	Commits graphQLCommits `json:"commits"`
+}
+
+type graphQLTimelineItem struct {
+	Typename    string        `json:"__typename"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	SubmittedAt time.Time     `json:"submittedAt"`
+	Body        string        `json:"body"`
+	Actor       *graphQLLogin `json:"actor"`
+	Author      *graphQLLogin `json:"author"`
+	Commit      *struct {
+		CommittedDate time.Time `json:"committedDate"`
+		Author        *struct {
+			User *graphQLLogin `json:"user"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+type graphQLPullRequests struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []graphQLPullRequest `json:"nodes"`
+}
+
+type graphQLRepository struct {
+	PullRequests graphQLPullRequests `json:"pullRequests"`
+}
+
+// getPullRequestsGraphQL fetches the open PRs of repos in batches of
+// graphQLBatchSize repositories per query. Repositories with more PRs than
+// fit in one page are continued in the next query with their cursor.
+// Failures of individual repositories, such as a renamed repository, are
+// returned as FetchErrors; err is set only if a whole query failed.
+func (c *Client) getPullRequestsGraphQL(ctx context.Context, owner string, repos []string) (prs []*PullRequest, failed FetchErrors, err error) {
+	cursors := make(map[string]string, len(repos))
+	pending := append([]string{}, repos...)
+	for len(pending) > 0 {
+		batch := pending
+		if len(batch) > graphQLBatchSize {
+			batch = batch[:graphQLBatchSize]
+		}
+		pending = pending[len(batch):]
+
+		var query strings.Builder
+		fields := pullRequestFields
+		if c.checks.Enabled {
+			fields += checkFields
+		}
+		query.WriteString("query {\n")
+		for i, repo := range batch {
+			after := "null"
+			if cursor, ok := cursors[repo]; ok {
+				after = quoteGraphQL(cursor)
+			}
+			fmt.Fprintf(&query, "r%d: repository(owner: %s, name: %s) { pullRequests(states: OPEN, first: %d, after: %s, orderBy: {field: CREATED_AT, direction: ASC}) { pageInfo { hasNextPage endCursor } nodes { %s } } }\n",
+				i, quoteGraphQL(owner), quoteGraphQL(repo), graphQLPageSize, after, fields)
+		}
+		query.WriteString("}")
+
+		var data map[string]*graphQLRepository
+		queryErrors, err := c.graphQL(ctx, owner, query.String(), &data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i, repo := range batch {
+			alias := fmt.Sprintf("r%d", i)
+			result := data[alias]
+			if result == nil {
+				msg := "repository not found"
+				if m, ok := queryErrors[alias]; ok {
+					msg = m
+				}
+				failed = append(failed, &RepoError{Owner: owner, Repo: repo, Err: errors.New(msg)})
+				continue
+			}
+			for _, pr := range result.PullRequests.Nodes {
+				converted := pr.toPullRequest(owner, repo)
+				if c.checks.Enabled {
+					converted.Checks = c.markRequired(converted, pr.Commits.checks(), pr.Commits.committed())
+				}
+				prs = append(prs, converted)
+			}
+			if result.PullRequests.PageInfo.HasNextPage {
+				cursors[repo] = result.PullRequests.PageInfo.EndCursor
+				pending = append(pending, repo)
+			}
+		}
+	}
+
+	return prs, failed, nil
+}
+
+// graphQL runs query with the client authenticated for owner and decodes
+// its data into v. Errors that belong to one top-level field are returned
+// keyed by that field's alias, so one bad repository does not fail the
+// others in its batch; any other error fails the query.
+func (c *Client) graphQL(ctx context.Context, owner, query string, v any) (map[string]string, error) {
+	client := c.forOwner(owner)
+
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := client.BaseURL.ResolveReference(&url.URL{Path: "../graphql"})
+	// Queries only read, so they are retried like GETs
+	req, err := http.NewRequestWithContext(withReadOnly(ctx), http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GraphQL request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GraphQL request failed: %s", resp.Status)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+			Path    []any  `json:"path"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid GraphQL response: %w", err)
+	}
+
+	fieldErrors := make(map[string]string)
+	var messages []string
+	for _, e := range result.Errors {
+		if len(e.Path) > 0 {
+			if alias, ok := e.Path[0].(string); ok {
+				fieldErrors[alias] = e.Message
+				continue
+			}
+		}
+		messages = append(messages, e.Message)
+	}
+	if len(messages) > 0 || len(result.Data) == 0 || string(result.Data) == "null" {
+		if len(messages) == 0 {
+			messages = append(messages, "no data returned")
+		}
+		return nil, fmt.Errorf("GraphQL query failed: %s", strings.Join(messages, "; "))
+	}
+	return fieldErrors, json.Unmarshal(result.Data, v)
+}
+
+// quoteGraphQL quotes s as a GraphQL string literal, whose escapes are the
+// same as JSON's
+func quoteGraphQL(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func (l *graphQLLogin) login() string {
+	if l == nil {
+		return ""
+	}
+	if l.Login != "" {
+		return l.Login
+	}
+	return l.Slug
+}
+
+func (pr *graphQLPullRequest) toPullRequest(owner, repo string) *PullRequest {
+	totalChanges := pr.Additions + pr.Deletions
+
+	var labels []string
+	for _, label := range pr.Labels.Nodes {
+		labels = append(labels, label.Name)
+	}
+	var requested []string
+	for _, request := range pr.ReviewRequests.Nodes {
+		if login := request.RequestedReviewer.login(); login != "" {
+			requested = append(requested, login)
+		}
+	}
+	reviews := make([]Review, 0, len(pr.Reviews.Nodes))
+	for _, review := range pr.Reviews.Nodes {
+		reviews = append(reviews, Review{
+			ID:          review.DatabaseID,
+			User:        review.Author.login(),
+			State:       review.State,
+			SubmittedAt: review.SubmittedAt,
+		})
+	}
+	var timeline []TimelineEvent
+	for _, item := range pr.TimelineItems.Nodes {
+		if event, ok := item.toTimelineEvent(); ok {
+			timeline = append(timeline, event)
+		}
+	}
+	approved, reviewCount := forge.SummarizeReviews(reviews)
+
+	user := &User{}
+	if pr.Author != nil {
+		user = &User{Login: pr.Author.Login, Email: pr.Author.Email, Name: pr.Author.Name}
+	}
+
+	return &PullRequest{
+		Number:             pr.Number,
+		Title:              pr.Title,
+		State:              strings.ToLower(pr.State),
+		Draft:              pr.IsDraft,
+		CreatedAt:          pr.CreatedAt,
+		UpdatedAt:          pr.UpdatedAt,
+		User:               user,
+		Head:               &Branch{Ref: pr.HeadRefName, SHA: pr.HeadRefOid},
+		Base:               &Branch{Ref: pr.BaseRefName, SHA: pr.BaseRefOid},
+		URL:                pr.URL,
+		Approved:           approved,
+		ReviewCount:        reviewCount,
+		Repo:               repo,
+		Owner:              owner,
+		Additions:          pr.Additions,
+		Deletions:          pr.Deletions,
+		TotalChanges:       totalChanges,
+		ChangedFiles:       pr.ChangedFiles,
+		SizeCategory:       forge.SizeCategory(totalChanges),
+		Labels:             labels,
+		RequestedReviewers: requested,
+		Reviews:            reviews,
+		Timeline:           timeline,
+	}
+}
+
+func (item *graphQLTimelineItem) toTimelineEvent() (TimelineEvent, bool) {
+	switch item.Typename {
+	case "PullRequestCommit":
+		if item.Commit == nil {
+			return TimelineEvent{}, false
+		}
+		var actor string
+		if item.Commit.Author != nil {
+			actor = item.Commit.Author.User.login()
+		}
+		return TimelineEvent{Type: "commit", Actor: actor, CreatedAt: item.Commit.CommittedDate}, true
+	case "HeadRefForcePushedEvent":
+		return TimelineEvent{Type: "force_push", Actor: item.Actor.login(), CreatedAt: item.CreatedAt}, true
+	case "IssueComment":
+		// The watcher's own comments are not activity on the PR
+		if forge.IsWatcherComment(item.Body) {
+			return TimelineEvent{}, false
+		}
+		return TimelineEvent{Type: "comment", Actor: item.Author.login(), CreatedAt: item.CreatedAt, Command: forge.FindCommand(item.Body)}, true
+	case "PullRequestReview":
+		return TimelineEvent{Type: "review", Actor: item.Author.login(), CreatedAt: item.SubmittedAt}, true
+	case "ReadyForReviewEvent":
+		return TimelineEvent{Type: "ready_for_review", Actor: item.Actor.login(), CreatedAt: item.CreatedAt}, true
+	case "ReviewRequestedEvent":
+		return TimelineEvent{Type: "review_requested", Actor: item.Actor.login(), CreatedAt: item.CreatedAt}, true
+	}
+	return TimelineEvent{}, false
+}
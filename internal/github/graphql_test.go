@@ -0,0 +1,137 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+func TestGetPullRequestsGraphQL(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var body struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		queries = append(queries, body.Query)
+
+		if strings.Contains(body.Query, `after: "cursor-1"`) {
+			w.Write([]byte(`{"data": {"r0": {"pullRequests": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"number": 2, "title": "Second", "state": "OPEN", "isDraft": true, "additions": 600, "deletions": 10}
+			]}}}}`))
+			return
+		}
+		w.Write([]byte(`{"data": {
+			"r0": {"pullRequests": {"pageInfo": {"hasNextPage": true, "endCursor": "cursor-1"}, "nodes": [
+				{"number": 1, "title": "First", "state": "OPEN", "additions": 10, "deletions": 5,
+				 "author": {"login": "alice"}, "headRefName": "feature", "headRefOid": "abc",
+				 "labels": {"nodes": [{"name": "bug"}]},
+				 "reviewRequests": {"nodes": [{"requestedReviewer": {"login": "bob"}}, {"requestedReviewer": {"slug": "core"}}]},
+				 "reviews": {"nodes": [{"state": "APPROVED", "author": {"login": "carol"}}, {"state": "COMMENTED", "author": {"login": "dave"}}]},
+				 "timelineItems": {"nodes": [
+					{"__typename": "PullRequestCommit", "commit": {"committedDate": "2024-01-02T00:00:00Z", "author": {"user": {"login": "alice"}}}},
+					{"__typename": "IssueComment", "createdAt": "2024-01-03T00:00:00Z", "author": {"login": "bob"}},
+					{"__typename": "IssueComment", "createdAt": "2024-01-04T00:00:00Z", "body": "<!-- pr-watcher:reminder -->\n### Needs approval", "author": {"login": "bob"}},
+					{"__typename": "IssueComment", "createdAt": "2024-01-05T00:00:00Z", "body": "/pr-watcher snooze 1d", "author": {"login": "bob"}}
+				 ]}}
+			]}},
+			"r1": {"pullRequests": {"pageInfo": {"hasNextPage": false}, "nodes": []}}
+		}}`))
+	}))
+	defer server.Close()
+
+	gh, err := github.NewClient(nil).WithEnterpriseURLs(server.URL+"/api/v3/", server.URL+"/api/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{client: gh, api: "graphql"}
+
+	prs, err := client.GetPullRequests(context.Background(), "acme", []string{"api", "web"})
+	if err != nil {
+		t.Fatalf("GetPullRequests failed: %v", err)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 queries (one batch plus one continuation), got %d", len(queries))
+	}
+	if len(prs) != 2 {
+		t.Fatalf("Expected 2 PRs, got %d", len(prs))
+	}
+
+	pr := prs[0]
+	if pr.FullName() != "acme/api" || pr.User.Login != "alice" || pr.Head.SHA != "abc" || pr.State != "open" {
+		t.Errorf("Unexpected PR fields: %+v", pr)
+	}
+	if !pr.Approved || pr.ReviewCount != 1 {
+		t.Errorf("Expected approved with 1 review, got approved=%v count=%d", pr.Approved, pr.ReviewCount)
+	}
+	if strings.Join(pr.Labels, ",") != "bug" || strings.Join(pr.RequestedReviewers, ",") != "bob,core" {
+		t.Errorf("Unexpected labels %v or requested reviewers %v", pr.Labels, pr.RequestedReviewers)
+	}
+	// The reminder comment is dropped, but not the token owner's own comments
+	if len(pr.Timeline) != 3 || pr.Timeline[0].Type != "commit" || pr.Timeline[1].Actor != "bob" || pr.Timeline[2].Command != "/pr-watcher snooze 1d" {
+		t.Errorf("Unexpected timeline %+v", pr.Timeline)
+	}
+	if !prs[1].Draft || prs[1].SizeCategory != "L" {
+		t.Errorf("Expected large draft PR, got draft=%v size=%s", prs[1].Draft, prs[1].SizeCategory)
+	}
+}
+
+func TestGraphQLChecks(t *testing.T) {
+	committed := time.Now().Add(-10 * time.Minute).UTC().Truncate(time.Second)
+	var pr graphQLPullRequest
+	err := json.Unmarshal([]byte(`{"commits": {"nodes": [{"commit": {"committedDate": "`+committed.Format(time.RFC3339)+`", "statusCheckRollup": {"contexts": {"nodes": [
+		{"__typename": "CheckRun", "name": "build", "status": "COMPLETED", "conclusion": "SUCCESS"},
+		{"__typename": "CheckRun", "name": "lint", "status": "COMPLETED", "conclusion": "FAILURE"},
+		{"__typename": "StatusContext", "context": "deploy/preview", "state": "SUCCESS"}
+	]}}}}]}}`), &pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{checks: config.ChecksConfig{
+		Enabled:        true,
+		Required:       []string{"Build", "deploy/preview", "test"},
+		Repos:          map[string][]string{"acme/legacy": {"jenkins"}},
+		MissingTimeout: time.Hour,
+	}}
+	checks := client.markRequired(&PullRequest{Owner: "acme", Repo: "api"}, pr.Commits.checks(), pr.Commits.committed())
+	want := []Check{
+		{Name: "build", State: forge.CIPassing, Required: true, Timeout: time.Hour},
+		{Name: "lint", State: forge.CIFailing},
+		{Name: "deploy/preview", State: forge.CIPassing, Required: true, Timeout: time.Hour},
+		{Name: "test", State: forge.CIPending, Required: true, Since: committed, Timeout: time.Hour},
+	}
+	if !slices.Equal(checks, want) {
+		t.Fatalf("checks = %+v, want %+v", checks, want)
+	}
+
+	// Only the required checks count, so the failed lint does not, and the
+	// test that has not reported yet is pending
+	converted := PullRequest{Checks: checks}
+	if state := converted.CIState(); state != forge.CIPending {
+		t.Errorf("CIState() = %q, want %q", state, forge.CIPending)
+	}
+
+	// A repository with its own required checks does not wait on the
+	// others, and one that never reports stops counting after the timeout
+	legacy := &PullRequest{Owner: "acme", Repo: "legacy"}
+	legacy.Checks = client.markRequired(legacy, pr.Commits.checks(), committed.Add(-2*time.Hour))
+	if state := legacy.CIState(); state != forge.CIUnknown {
+		t.Errorf("CIState() of a PR without its required check = %q, want %q", state, forge.CIUnknown)
+	}
+	if missing := legacy.MissingChecks(); !slices.Equal(missing, []string{"jenkins"}) {
+		t.Errorf("MissingChecks() = %v, want [jenkins]", missing)
+	}
+}
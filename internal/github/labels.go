@@ -0,0 +1,41 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// CreateLabel creates a repository label with the given color. A label of
+// that name that already exists is left as it is.
+func (c *Client) CreateLabel(ctx context.Context, owner, repo, name, color string) error {
+	_, _, err := c.forOwner(owner).Issues.CreateLabel(ctx, owner, repo, &github.Label{Name: &name, Color: &color})
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusUnprocessableEntity {
+		for _, e := range errResp.Errors {
+			if e.Code == "already_exists" {
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// AddLabels adds labels to a pull request
+func (c *Client) AddLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	_, _, err := c.forOwner(owner).Issues.AddLabelsToIssue(ctx, owner, repo, number, labels)
+	return err
+}
+
+// RemoveLabel removes a label from a pull request. A label the pull request
+// no longer has is not an error.
+func (c *Client) RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error {
+	_, err := c.forOwner(owner).Issues.RemoveLabelForIssue(ctx, owner, repo, number, label)
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,357 @@
+package github
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+)
+
+// rateLimitTransport paces requests against the X-RateLimit-* headers of
+// the previous responses, retries secondary rate limits and server errors
+// with jittered backoff, and revalidates cached GET responses with
+// If-None-Match so unchanged resources do not count against the quota
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	cfg     config.RateLimitConfig
+	timeout time.Duration // Per attempt, zero for none
+	cache   *etagCache
+
+	mu     sync.Mutex
+	limits map[string]*rateLimit // keyed by X-RateLimit-Resource: core, graphql, search
+}
+
+type rateLimit struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+func newRateLimitTransport(base http.RoundTripper, cfg config.RateLimitConfig, timeout time.Duration) *rateLimitTransport {
+	t := &rateLimitTransport{
+		base:    base,
+		cfg:     cfg,
+		timeout: timeout,
+		limits:  make(map[string]*rateLimit),
+	}
+	if cfg.CacheSize > 0 {
+		t.cache = newETagCache(cfg.CacheSize)
+	}
+	return t
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := rateLimitResource(req)
+	if err := t.waitForQuota(req.Context(), resource); err != nil {
+		return nil, err
+	}
+
+	var cached *cachedResponse
+	cacheKey := req.URL.String() + " " + req.Header.Get("Accept")
+	if t.cache != nil && req.Method == http.MethodGet {
+		if cached = t.cache.get(cacheKey); cached != nil {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+		if err != nil {
+			return nil, err
+		}
+		t.record(resource, resp.Header)
+
+		if wait, retry := t.retryDelay(req, resp, attempt); retry {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			logger.Debug("GitHub returned %s for %s %s, retrying in %v (attempt %d of %d)",
+				resp.Status, req.Method, req.URL.Path, wait.Round(time.Millisecond), attempt+1, t.cfg.MaxRetries)
+			if err := sleep(req.Context(), wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
+			resp.Body.Close()
+			return cached.response(req, resp.Header), nil
+		}
+		if t.cache != nil && req.Method == http.MethodGet && resp.StatusCode == http.StatusOK {
+			if etag := resp.Header.Get("ETag"); etag != "" {
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				t.cache.put(cacheKey, &cachedResponse{etag: etag, header: resp.Header.Clone(), body: body})
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+			}
+		}
+		return resp, nil
+	}
+}
+
+// attempt sends req once within the per-attempt timeout. The body is read
+// before the timeout is released so callers can consume it afterwards.
+func (t *rateLimitTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	defer cancel()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// waitForQuota sleeps until the rate limit resets when fewer than
+// min_remaining requests are left. With requests left but a reset further
+// away than max_wait, the reserve is used instead of waiting; with none left
+// the request fails.
+func (t *rateLimitTransport) waitForQuota(ctx context.Context, resource string) error {
+	t.mu.Lock()
+	limit, ok := t.limits[resource]
+	if !ok || limit.remaining > t.cfg.MinRemaining || !time.Now().Before(limit.reset) {
+		if ok {
+			limit.remaining--
+		}
+		t.mu.Unlock()
+		return nil
+	}
+	wait := time.Until(limit.reset) + time.Second
+	remaining, total, reset := limit.remaining, limit.limit, limit.reset
+	if wait > t.cfg.MaxWait {
+		limit.remaining--
+		t.mu.Unlock()
+		if remaining > 0 {
+			return nil
+		}
+		return fmt.Errorf("GitHub %s rate limit exhausted until %s", resource, reset.Format(time.RFC3339))
+	}
+	t.mu.Unlock()
+
+	logger.Info("GitHub %s rate limit nearly exhausted (%d of %d left), waiting %v for the reset",
+		resource, remaining, total, wait.Round(time.Second))
+	if err := sleep(ctx, wait); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	if limit.reset.Equal(reset) {
+		limit.remaining = limit.limit
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// record remembers the rate limit reported by a response
+func (t *rateLimitTransport) record(resource string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	total, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if r := header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limits[resource] = &rateLimit{limit: total, remaining: remaining, reset: time.Unix(reset, 0)}
+}
+
+// retryDelay decides whether resp is worth retrying and after how long:
+// secondary rate limits, an exhausted primary limit that resets within
+// max_wait, and 5xx server errors of idempotent requests are. A write that
+// failed with a 5xx may still have happened, so it is not repeated.
+func (t *rateLimitTransport) retryDelay(req *http.Request, resp *http.Response, attempt int) (time.Duration, bool) {
+	if attempt >= t.cfg.MaxRetries {
+		return 0, false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return t.backoff(attempt), idempotent(req)
+	case http.StatusForbidden, http.StatusTooManyRequests:
+	default:
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		wait := time.Duration(seconds) * time.Second
+		return wait, wait <= t.cfg.MaxWait
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		wait := time.Until(time.Unix(reset, 0)) + time.Second
+		return wait, wait <= t.cfg.MaxWait
+	}
+
+	// Secondary rate limits are only recognizable by their message; keep
+	// the body readable for the caller if this is not one
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit") {
+		return t.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// backoff returns retry_backoff doubled per attempt, with jitter so that
+// concurrent requests do not retry in lockstep
+func (t *rateLimitTransport) backoff(attempt int) time.Duration {
+	d := t.cfg.RetryBackoff << attempt
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+func rateLimitResource(req *http.Request) string {
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	case strings.Contains(req.URL.Path, "/search/"):
+		return "search"
+	default:
+		return "core"
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// etagCache is a size-bounded LRU cache of GET responses with an ETag
+type etagCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // Most recently used first; values are cache keys
+	entries map[string]*list.Element
+	values  map[string]*cachedResponse
+}
+
+type cachedResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func newETagCache(size int) *etagCache {
+	return &etagCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		values:  make(map[string]*cachedResponse),
+	}
+}
+
+func (c *etagCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return c.values[key]
+}
+
+func (c *etagCache) put(key string, value *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(key)
+	}
+	c.values[key] = value
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(string))
+		delete(c.values, oldest.Value.(string))
+	}
+}
+
+// response rebuilds the cached response for a 304, carrying over the fresh
+// rate limit headers of the revalidation
+func (r *cachedResponse) response(req *http.Request, fresh http.Header) *http.Response {
+	header := r.header.Clone()
+	for name, values := range fresh {
+		if strings.HasPrefix(name, "X-Ratelimit-") {
+			header[name] = values
+		}
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
+
+// readOnlyKey marks the context of a request that only reads, such as a
+// GraphQL query, which is safe to repeat even though it is a POST
+type readOnlyKey struct{}
+
+// withReadOnly returns ctx marking its requests as read-only
+func withReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// idempotent reports whether req can be sent again without repeating a
+// side effect
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	}
+	readOnly, _ := req.Context().Value(readOnlyKey{}).(bool)
+	return readOnly
+}
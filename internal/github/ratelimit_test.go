@@ -0,0 +1,112 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+)
+
+func TestRateLimitTransport_RetriesAndETags(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4000")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		switch {
+		case requests == 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case requests == 2:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+		case r.Header.Get("If-None-Match") == `"v1"`:
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"ok": true}`))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newRateLimitTransport(http.DefaultTransport, config.RateLimitConfig{
+		MinRemaining: 50,
+		MaxWait:      time.Minute,
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+		CacheSize:    10,
+	}, time.Second)}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/repos/acme/api/pulls")
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body[:n]) != `{"ok": true}` {
+			t.Errorf("Request %d: expected cached 200 body, got %d %q", i, resp.StatusCode, body[:n])
+		}
+	}
+	if requests != 4 {
+		t.Errorf("Expected 2 retries, 1 fetch and 1 revalidation, got %d requests", requests)
+	}
+}
+
+func TestRateLimitTransport_FailsWhenExhaustedBeyondMaxWait(t *testing.T) {
+	transport := newRateLimitTransport(http.DefaultTransport, config.RateLimitConfig{MinRemaining: 10, MaxWait: time.Minute}, 0)
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "5000")
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	transport.record("core", header)
+
+	req := httptest.NewRequest(http.MethodGet, "https://api.github.com/repos/acme/api", nil)
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Error("Expected an error while the rate limit is exhausted")
+	}
+}
+
+func TestRateLimitTransport_DoesNotRetryWritesOnServerErrors(t *testing.T) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newRateLimitTransport(http.DefaultTransport, config.RateLimitConfig{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	}, time.Second)}
+
+	// The comment may have been created despite the 502, so it is not posted again
+	resp, err := client.Post(server.URL+"/repos/acme/api/issues/1/comments", "application/json", strings.NewReader(`{"body": "hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || requests["/repos/acme/api/issues/1/comments"] != 1 {
+		t.Errorf("Expected a single POST returning 502, got %d requests and status %d", requests["/repos/acme/api/issues/1/comments"], resp.StatusCode)
+	}
+
+	// A read-only query is retried like a GET
+	req, err := http.NewRequestWithContext(withReadOnly(context.Background()), http.MethodPost, server.URL+"/graphql", strings.NewReader(`{"query": "{}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if requests["/graphql"] != 3 {
+		t.Errorf("Expected the GraphQL query to be retried twice, got %d requests", requests["/graphql"])
+	}
+}
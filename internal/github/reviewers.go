@@ -0,0 +1,86 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+// codeOwnersPaths are the locations GitHub reads CODEOWNERS from, in order
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// RequestReviewers requests reviews on a pull request. Reviewers of the form
+// "org/team" are requested as teams of the pull request's organization.
+func (c *Client) RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers []string) error {
+	var request github.ReviewersRequest
+	for _, reviewer := range reviewers {
+		if _, team, ok := strings.Cut(reviewer, "/"); ok {
+			request.TeamReviewers = append(request.TeamReviewers, team)
+		} else {
+			request.Reviewers = append(request.Reviewers, reviewer)
+		}
+	}
+	_, _, err := c.forOwner(owner).PullRequests.RequestReviewers(ctx, owner, repo, number, request)
+	return err
+}
+
+// CodeOwners returns the owners of the files changed by a pull request,
+// from the first CODEOWNERS file found on its base branch. Without a
+// CODEOWNERS file there are no owners.
+func (c *Client) CodeOwners(ctx context.Context, pr *PullRequest) ([]string, error) {
+	client := c.forOwner(pr.Owner)
+
+	var ref string
+	if pr.Base != nil {
+		ref = pr.Base.Ref
+	}
+	var owners forge.CodeOwners
+	for _, path := range codeOwnersPaths {
+		file, _, _, err := client.Repositories.GetContents(ctx, pr.Owner, pr.Repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", path, err)
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		owners = forge.ParseCodeOwners([]byte(content))
+		break
+	}
+	if len(owners) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := client.PullRequests.ListFiles(ctx, pr.Owner, pr.Repo, pr.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list changed files: %w", err)
+		}
+		for _, file := range files {
+			for _, owner := range owners.Owners(file.GetFilename()) {
+				if key := strings.ToLower(owner); !seen[key] {
+					seen[key] = true
+					result = append(result, owner)
+				}
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return result, nil
+}
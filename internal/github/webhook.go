@@ -0,0 +1,153 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+// ErrInvalidSignature is returned by ParseWebhook when the payload is not
+// signed with the configured secret
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookEvent is a change to a pull request or to the checks of a commit,
+// delivered by a GitHub webhook
+type WebhookEvent struct {
+	Event       string         // X-GitHub-Event, e.g. pull_request or pull_request_review
+	Action      string         // e.g. opened, closed, submitted, resolved
+	Owner       string         // Repository owner
+	Repo        string         // Repository name
+	Number      int            // Pull request number
+	PullRequest *PullRequest   // State of the PR in the payload; reviews are not included. Nil for comments.
+	Review      *Review        // Set for pull_request_review events
+	Comment     *TimelineEvent // Set for new comments on a PR, other than the watcher's own
+	SHA         string         // Commit of check_run and status events, and of completed check suites
+	Check       *Check         // Set for check_run and status events
+	Sender      string         // Login of the user who triggered the event
+	At          time.Time      // When the event was received
+}
+
+// ParseWebhook verifies the X-Hub-Signature-256 of a webhook request against
+// secret and decodes pull_request, pull_request_review and
+// pull_request_review_thread events, issue_comment events on pull
+// requests, and the check_run, check_suite and status events of commits.
+// Other events, such as ping, are returned with only Event set.
+func ParseWebhook(r *http.Request, secret []byte) (*WebhookEvent, error) {
+	payload, err := github.ValidatePayload(r, secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	event := &WebhookEvent{Event: github.WebHookType(r), At: time.Now()}
+	switch event.Event {
+	case "pull_request", "pull_request_review", "pull_request_review_thread":
+	case "issue_comment":
+		return parseCommentWebhook(event, payload)
+	case "check_run", "check_suite", "status":
+		return parseCheckWebhook(event, payload)
+	default:
+		return event, nil
+	}
+
+	parsed, err := github.ParseWebHook(event.Event, payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", event.Event, err)
+	}
+
+	var (
+		repo *github.Repository
+		pr   *github.PullRequest
+	)
+	switch e := parsed.(type) {
+	case *github.PullRequestEvent:
+		event.Action, repo, pr, event.Sender = e.GetAction(), e.GetRepo(), e.GetPullRequest(), e.GetSender().GetLogin()
+	case *github.PullRequestReviewEvent:
+		event.Action, repo, pr, event.Sender = e.GetAction(), e.GetRepo(), e.GetPullRequest(), e.GetSender().GetLogin()
+		review := e.GetReview()
+		event.Review = &Review{
+			ID:          review.GetID(),
+			User:        review.GetUser().GetLogin(),
+			State:       strings.ToUpper(review.GetState()),
+			SubmittedAt: review.GetSubmittedAt().Time,
+		}
+	case *github.PullRequestReviewThreadEvent:
+		event.Action, repo, pr, event.Sender = e.GetAction(), e.GetRepo(), e.GetPullRequest(), e.GetSender().GetLogin()
+	}
+	if repo == nil || pr == nil {
+		return nil, fmt.Errorf("invalid %s payload: missing repository or pull request", event.Event)
+	}
+
+	event.Owner = repo.GetOwner().GetLogin()
+	event.Repo = repo.GetName()
+	event.PullRequest = newPullRequest(event.Owner, event.Repo, pr, nil)
+	event.Number = event.PullRequest.Number
+	return event, nil
+}
+
+// parseCommentWebhook decodes an issue_comment event. New comments on pull
+// requests set Comment, except those the watcher wrote itself.
+func parseCommentWebhook(event *WebhookEvent, payload []byte) (*WebhookEvent, error) {
+	parsed, err := github.ParseWebHook(event.Event, payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", event.Event, err)
+	}
+	e, ok := parsed.(*github.IssueCommentEvent)
+	if !ok || e.GetRepo() == nil || e.GetIssue() == nil {
+		return nil, fmt.Errorf("invalid %s payload: missing repository or issue", event.Event)
+	}
+	event.Action, event.Sender = e.GetAction(), e.GetSender().GetLogin()
+	event.Owner = e.GetRepo().GetOwner().GetLogin()
+	event.Repo = e.GetRepo().GetName()
+	event.Number = e.GetIssue().GetNumber()
+
+	body := e.GetComment().GetBody()
+	if event.Action != "created" || !e.GetIssue().IsPullRequest() || forge.IsWatcherComment(body) {
+		return event, nil
+	}
+	event.Comment = &TimelineEvent{
+		Type:      "comment",
+		Actor:     e.GetComment().GetUser().GetLogin(),
+		CreatedAt: e.GetComment().GetCreatedAt().Time,
+		Command:   forge.FindCommand(body),
+	}
+	return event, nil
+}
+
+// parseCheckWebhook decodes a check_run, check_suite or status event. Check
+// runs and statuses set SHA and Check. A check suite names none of its runs,
+// so only a completed one sets SHA, leaving Check nil.
+func parseCheckWebhook(event *WebhookEvent, payload []byte) (*WebhookEvent, error) {
+	parsed, err := github.ParseWebHook(event.Event, payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", event.Event, err)
+	}
+
+	var repo *github.Repository
+	switch e := parsed.(type) {
+	case *github.CheckRunEvent:
+		event.Action, repo, event.Sender = e.GetAction(), e.GetRepo(), e.GetSender().GetLogin()
+		run := e.GetCheckRun()
+		event.SHA = run.GetHeadSHA()
+		event.Check = &Check{Name: run.GetName(), State: checkRunState(run.GetStatus(), run.GetConclusion())}
+	case *github.CheckSuiteEvent:
+		event.Action, repo, event.Sender = e.GetAction(), e.GetRepo(), e.GetSender().GetLogin()
+		if event.Action == "completed" {
+			event.SHA = e.GetCheckSuite().GetHeadSHA()
+		}
+	case *github.StatusEvent:
+		repo, event.Sender = e.GetRepo(), e.GetSender().GetLogin()
+		event.SHA = e.GetSHA()
+		event.Check = &Check{Name: e.GetContext(), State: statusState(e.GetState())}
+	}
+	if repo == nil {
+		return nil, fmt.Errorf("invalid %s payload: missing repository", event.Event)
+	}
+	event.Owner = repo.GetOwner().GetLogin()
+	event.Repo = repo.GetName()
+	return event, nil
+}
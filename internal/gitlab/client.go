@@ -0,0 +1,332 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+// Client lists GitLab merge requests through the REST API (v4). Merge
+// requests are mapped onto forge.PullRequest: approvals become APPROVED
+// reviews and the diff stats are counted from the MR diffs.
+type Client struct {
+	baseURL *url.URL // API root, e.g. https://gitlab.example.com/api/v4/
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for a gitlab source
+func NewClient(cfg config.SourceConfig) (*Client, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("GitLab token is required")
+	}
+	base, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/") + "/api/v4/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitLab base URL: %w", err)
+	}
+	return &Client{
+		baseURL: base,
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// APIError is a non-2xx response of the GitLab API
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("GitLab API returned %d", e.Status)
+	}
+	return fmt.Sprintf("GitLab API returned %d: %s", e.Status, e.Message)
+}
+
+// get decodes the response of a GET request into v and returns the next
+// page number, or 0 on the last page
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) (int, error) {
+	// path is already escaped: project IDs contain %2F
+	u := c.baseURL.String() + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Message any    `json:"message"`
+			Error   string `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+		msg := body.Error
+		if body.Message != nil {
+			msg = fmt.Sprint(body.Message)
+		}
+		return 0, &APIError{Status: resp.StatusCode, Message: msg}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return 0, fmt.Errorf("invalid GitLab API response for %s: %w", path, err)
+	}
+	next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return next, nil
+}
+
+// projectPath returns the escaped API path of a project
+func projectPath(owner, repo string) string {
+	return "projects/" + url.PathEscape(owner+"/"+repo)
+}
+
+type mergeRequest struct {
+	IID          int          `json:"iid"`
+	Title        string       `json:"title"`
+	State        string       `json:"state"`
+	Draft        bool         `json:"draft"`
+	WorkInProg   bool         `json:"work_in_progress"` // Before GitLab 13.2 drafts were "WIP"
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	Author       gitlabUser   `json:"author"`
+	SourceBranch string       `json:"source_branch"`
+	TargetBranch string       `json:"target_branch"`
+	SHA          string       `json:"sha"`
+	WebURL       string       `json:"web_url"`
+	Labels       []string     `json:"labels"`
+	Reviewers    []gitlabUser `json:"reviewers"`
+	DiffRefs     struct {
+		BaseSHA string `json:"base_sha"`
+	} `json:"diff_refs"`
+}
+
+type gitlabUser struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+type approvals struct {
+	ApprovedBy []struct {
+		User gitlabUser `json:"user"`
+	} `json:"approved_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type diff struct {
+	Diff string `json:"diff"`
+}
+
+// GetPullRequests returns the open merge requests of repos of owner, which
+// is a user or a group path such as "group/subgroup"
+func (c *Client) GetPullRequests(ctx context.Context, owner string, repos []string) ([]*forge.PullRequest, error) {
+	var (
+		prs    []*forge.PullRequest
+		failed forge.FetchErrors
+	)
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return prs, err
+		}
+		repoPRs, err := c.getMergeRequests(ctx, owner, repo)
+		if err != nil {
+			failed = append(failed, &forge.RepoError{Owner: owner, Repo: repo, Err: err})
+			continue
+		}
+		prs = append(prs, repoPRs...)
+	}
+	if len(failed) > 0 {
+		return prs, failed
+	}
+	return prs, nil
+}
+
+func (c *Client) getMergeRequests(ctx context.Context, owner, repo string) ([]*forge.PullRequest, error) {
+	query := url.Values{"state": {"opened"}, "per_page": {"100"}}
+	var prs []*forge.PullRequest
+	for page := 1; page != 0; {
+		query.Set("page", strconv.Itoa(page))
+		var mrs []mergeRequest
+		next, err := c.get(ctx, projectPath(owner, repo)+"/merge_requests", query, &mrs)
+		if err != nil {
+			return nil, err
+		}
+		for _, mr := range mrs {
+			pr, err := c.newPullRequest(ctx, owner, repo, mr)
+			if err != nil {
+				return nil, fmt.Errorf("merge request !%d: %w", mr.IID, err)
+			}
+			prs = append(prs, pr)
+		}
+		page = next
+	}
+	return prs, nil
+}
+
+// GetPRDetails returns a single merge request with its approvals
+func (c *Client) GetPRDetails(ctx context.Context, owner, repo string, number int) (*forge.PullRequest, error) {
+	var mr mergeRequest
+	if _, err := c.get(ctx, fmt.Sprintf("%s/merge_requests/%d", projectPath(owner, repo), number), nil, &mr); err != nil {
+		return nil, err
+	}
+	return c.newPullRequest(ctx, owner, repo, mr)
+}
+
+// newPullRequest maps a merge request onto the forge model, fetching its
+// approvals and diff stats
+func (c *Client) newPullRequest(ctx context.Context, owner, repo string, mr mergeRequest) (*forge.PullRequest, error) {
+	mrPath := fmt.Sprintf("%s/merge_requests/%d", projectPath(owner, repo), mr.IID)
+
+	var approved approvals
+	if _, err := c.get(ctx, mrPath+"/approvals", nil, &approved); err != nil {
+		return nil, fmt.Errorf("failed to get approvals: %w", err)
+	}
+	reviews := make([]forge.Review, 0, len(approved.ApprovedBy))
+	for _, a := range approved.ApprovedBy {
+		reviews = append(reviews, forge.Review{User: a.User.Username, State: "APPROVED", SubmittedAt: approved.UpdatedAt})
+	}
+
+	additions, deletions, files, err := c.diffStats(ctx, mrPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff stats: %w", err)
+	}
+
+	var requested []string
+	for _, reviewer := range mr.Reviewers {
+		requested = append(requested, reviewer.Username)
+	}
+
+	pr := &forge.PullRequest{
+		Number:    mr.IID,
+		Title:     mr.Title,
+		State:     "open",
+		Draft:     mr.Draft || mr.WorkInProg,
+		CreatedAt: mr.CreatedAt,
+		UpdatedAt: mr.UpdatedAt,
+		User: &forge.User{
+			Login: mr.Author.Username,
+			Name:  mr.Author.Name,
+		},
+		Head: &forge.Branch{
+			Ref: mr.SourceBranch,
+			SHA: mr.SHA,
+		},
+		Base: &forge.Branch{
+			Ref: mr.TargetBranch,
+			SHA: mr.DiffRefs.BaseSHA,
+		},
+		URL:                mr.WebURL,
+		Repo:               repo,
+		Owner:              owner,
+		Labels:             mr.Labels,
+		RequestedReviewers: requested,
+	}
+	pr.SetReviews(reviews)
+	pr.SetSize(additions, deletions, files)
+	return pr, nil
+}
+
+// diffStats counts the added and removed lines and the changed files of a
+// merge request from its diffs, which are hunks without file headers
+func (c *Client) diffStats(ctx context.Context, mrPath string) (additions, deletions, files int, err error) {
+	query := url.Values{"per_page": {"100"}}
+	for page := 1; page != 0; {
+		query.Set("page", strconv.Itoa(page))
+		var diffs []diff
+		next, err := c.get(ctx, mrPath+"/diffs", query, &diffs)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, d := range diffs {
+			files++
+			for _, line := range strings.Split(d.Diff, "\n") {
+				switch {
+				case strings.HasPrefix(line, "+"):
+					additions++
+				case strings.HasPrefix(line, "-"):
+					deletions++
+				}
+			}
+		}
+		page = next
+	}
+	return additions, deletions, files, nil
+}
+
+type project struct {
+	Path       string   `json:"path"`
+	PathWithNS string   `json:"path_with_namespace"`
+	Visibility string   `json:"visibility"`
+	Archived   bool     `json:"archived"`
+	Topics     []string `json:"topics"`
+	ForkedFrom *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+}
+
+// ListRepositories lists the projects of a group, including those of its
+// subgroups, or of a user. Names are relative to owner, so a project in a
+// subgroup is named "subgroup/project".
+func (c *Client) ListRepositories(ctx context.Context, owner string) ([]*forge.Repository, error) {
+	query := url.Values{"include_subgroups": {"true"}, "per_page": {"100"}}
+	path := "groups/" + url.PathEscape(owner) + "/projects"
+
+	var repos []*forge.Repository
+	for page := 1; page != 0; {
+		query.Set("page", strconv.Itoa(page))
+		var projects []project
+		next, err := c.get(ctx, path, query, &projects)
+		var apiErr *APIError
+		if page == 1 && errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound && !strings.Contains(owner, "/") {
+			// Not a group; try a user namespace
+			path = "users/" + url.PathEscape(owner) + "/projects"
+			query.Del("include_subgroups")
+			next, err = c.get(ctx, path, query, &projects)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list projects of %s: %w", owner, err)
+		}
+		for _, p := range projects {
+			name := strings.TrimPrefix(p.PathWithNS, owner+"/")
+			if name == p.PathWithNS {
+				name = p.Path
+			}
+			repos = append(repos, &forge.Repository{
+				Owner:      owner,
+				Name:       name,
+				Topics:     p.Topics,
+				Visibility: p.Visibility,
+				Archived:   p.Archived,
+				Fork:       p.ForkedFrom != nil,
+			})
+		}
+		page = next
+	}
+	return repos, nil
+}
+
+// Ping checks that the GitLab API is reachable and the token is accepted
+func (c *Client) Ping(ctx context.Context) error {
+	var user gitlabUser
+	_, err := c.get(ctx, "user", nil, &user)
+	return err
+}
@@ -0,0 +1,71 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+func TestClient_GetPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	project := "/api/v4/projects/platform%2Fbackend%2Fapi"
+	mux.HandleFunc(project+"/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "glpat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("state") != "opened" {
+			t.Errorf("state = %q, want opened", r.URL.Query().Get("state"))
+		}
+		w.Write([]byte(`[{"iid": 12, "title": "Draft: Add endpoint", "state": "opened", "draft": true,
+			"created_at": "2024-05-01T10:00:00Z", "author": {"username": "alice", "name": "Alice"},
+			"source_branch": "feature", "target_branch": "main", "sha": "abc123", "web_url": "https://gitlab.example.com/mr/12",
+			"labels": ["backend"], "reviewers": [{"username": "bob"}]}]`))
+	})
+	mux.HandleFunc(project+"/merge_requests/12/approvals", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"approved_by": [{"user": {"username": "carol"}}], "updated_at": "2024-05-02T10:00:00Z"}`))
+	})
+	mux.HandleFunc(project+"/merge_requests/12/diffs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"diff": "@@ -1,2 +1,3 @@\n line\n-old\n+new\n+added\n"}, {"diff": "@@ -0,0 +1 @@\n+file\n"}]`))
+	})
+	mux.HandleFunc("/api/v4/projects/platform%2Fbackend%2Fmissing/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "404 Project Not Found"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := NewClient(config.SourceConfig{BaseURL: server.URL, Token: "glpat", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prs, err := client.GetPullRequests(context.Background(), "platform/backend", []string{"api", "missing"})
+	var failed forge.FetchErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || failed[0].Repo != "missing" {
+		t.Fatalf("err = %v, want a FetchErrors for the missing project", err)
+	}
+	if len(prs) != 1 {
+		t.Fatalf("got %d PRs, want 1", len(prs))
+	}
+
+	pr := prs[0]
+	if pr.Number != 12 || !pr.Draft || pr.User.Login != "alice" || pr.FullName() != "platform/backend/api" {
+		t.Errorf("unexpected PR %+v", pr)
+	}
+	if !pr.Approved || pr.ReviewCount != 1 || pr.Reviews[0].User != "carol" {
+		t.Errorf("approvals not mapped to reviews: %+v", pr.Reviews)
+	}
+	if pr.Additions != 3 || pr.Deletions != 1 || pr.ChangedFiles != 2 || pr.SizeCategory != "XS" {
+		t.Errorf("diff stats = +%d -%d in %d files (%s), want +3 -1 in 2 files (XS)", pr.Additions, pr.Deletions, pr.ChangedFiles, pr.SizeCategory)
+	}
+	if len(pr.RequestedReviewers) != 1 || pr.RequestedReviewers[0] != "bob" {
+		t.Errorf("requested reviewers = %v, want [bob]", pr.RequestedReviewers)
+	}
+}
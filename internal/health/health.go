@@ -0,0 +1,221 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check probes an external dependency and returns an error if it is unreachable
+type Check func(ctx context.Context) error
+
+// Monitor tracks the outcome of watch loop runs and the reachability of the
+// services the watcher depends on
+type Monitor struct {
+	mu                     sync.Mutex
+	startedAt              time.Time
+	lastRun                time.Time
+	lastSuccess            time.Time
+	lastError              string
+	runStarted             time.Time
+	consecutiveFailures    int
+	maxConsecutiveFailures int
+	maxRunDuration         time.Duration
+	probeTimeout           time.Duration
+	checks                 map[string]Check
+}
+
+// Status is the JSON body returned by /healthz and /readyz
+type Status struct {
+	Status              string            `json:"status"`
+	StartedAt           time.Time         `json:"started_at"`
+	LastRun             *time.Time        `json:"last_run,omitempty"`
+	LastSuccess         *time.Time        `json:"last_success,omitempty"`
+	LastError           string            `json:"last_error,omitempty"`
+	RunInProgress       bool              `json:"run_in_progress"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	Checks              map[string]string `json:"checks,omitempty"`
+}
+
+func NewMonitor(maxConsecutiveFailures int, maxRunDuration, probeTimeout time.Duration) *Monitor {
+	return &Monitor{
+		startedAt:              time.Now(),
+		maxConsecutiveFailures: maxConsecutiveFailures,
+		maxRunDuration:         maxRunDuration,
+		probeTimeout:           probeTimeout,
+		checks:                 make(map[string]Check),
+	}
+}
+
+// AddCheck registers a dependency probe evaluated by /readyz
+func (m *Monitor) AddCheck(name string, check Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks[name] = check
+}
+
+// RemoveChecks unregisters every dependency probe, so they can be
+// registered again after the dependencies changed
+func (m *Monitor) RemoveChecks() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = make(map[string]Check)
+}
+
+// RunStarted marks the beginning of a watch loop run
+func (m *Monitor) RunStarted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runStarted = time.Now()
+}
+
+// RunFinished records the outcome of the run started by RunStarted
+func (m *Monitor) RunFinished(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.runStarted = time.Time{}
+	m.lastRun = now
+	if err != nil {
+		m.consecutiveFailures++
+		m.lastError = err.Error()
+		return
+	}
+	m.consecutiveFailures = 0
+	m.lastSuccess = now
+	m.lastError = ""
+}
+
+// Alive reports whether the watch loop is making progress: no run has been
+// in flight for longer than the maximum run duration and the consecutive
+// failure limit has not been reached
+func (m *Monitor) Alive() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.aliveLocked()
+}
+
+func (m *Monitor) aliveLocked() bool {
+	if !m.runStarted.IsZero() && m.maxRunDuration > 0 && time.Since(m.runStarted) > m.maxRunDuration {
+		return false
+	}
+	if m.maxConsecutiveFailures > 0 && m.consecutiveFailures >= m.maxConsecutiveFailures {
+		return false
+	}
+	return true
+}
+
+func (m *Monitor) snapshot() (Status, map[string]Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := Status{
+		StartedAt:           m.startedAt,
+		LastError:           m.lastError,
+		RunInProgress:       !m.runStarted.IsZero(),
+		ConsecutiveFailures: m.consecutiveFailures,
+	}
+	if !m.lastRun.IsZero() {
+		lastRun := m.lastRun
+		status.LastRun = &lastRun
+	}
+	if !m.lastSuccess.IsZero() {
+		lastSuccess := m.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+
+	checks := make(map[string]Check, len(m.checks))
+	for name, check := range m.checks {
+		checks[name] = check
+	}
+	return status, checks
+}
+
+// runChecks evaluates every registered probe concurrently and returns the
+// per-check result ("ok" or the error message) and whether all passed
+func (m *Monitor) runChecks(ctx context.Context, checks map[string]Check) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, m.probeTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, checks[name])
+	}
+	wg.Wait()
+
+	out := make(map[string]string, len(names))
+	ok := true
+	for i, name := range names {
+		if results[i] != nil {
+			out[name] = results[i].Error()
+			ok = false
+		} else {
+			out[name] = "ok"
+		}
+	}
+	return out, ok
+}
+
+// Handler returns an http.Handler serving /healthz and /readyz.
+//
+// /healthz reports whether the watch loop is alive (not stuck and not
+// failing repeatedly). /readyz additionally requires a successful run and
+// reachable GitHub and SMTP endpoints.
+func (m *Monitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	m.Register(mux)
+	return mux
+}
+
+// Register adds the health endpoints to an existing mux
+func (m *Monitor) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", m.handleHealthz)
+	mux.HandleFunc("/readyz", m.handleReadyz)
+}
+
+func (m *Monitor) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	status, _ := m.snapshot()
+
+	code := http.StatusOK
+	status.Status = "ok"
+	if !m.Alive() {
+		code = http.StatusServiceUnavailable
+		status.Status = "unhealthy"
+	}
+	writeStatus(w, code, status)
+}
+
+func (m *Monitor) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	status, checks := m.snapshot()
+	results, checksOK := m.runChecks(r.Context(), checks)
+	status.Checks = results
+
+	code := http.StatusOK
+	status.Status = "ready"
+	if status.LastSuccess == nil || !checksOK || !m.Alive() {
+		code = http.StatusServiceUnavailable
+		status.Status = "not ready"
+	}
+	writeStatus(w, code, status)
+}
+
+func writeStatus(w http.ResponseWriter, code int, status Status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
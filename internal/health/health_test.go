@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMonitor_Healthz(t *testing.T) {
+	m := NewMonitor(2, time.Minute, time.Second)
+	handler := m.Handler()
+
+	get := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec.Code
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected 200 before any run, got %d", code)
+	}
+
+	m.RunStarted()
+	m.RunFinished(errors.New("boom"))
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected 200 after one failure, got %d", code)
+	}
+
+	m.RunStarted()
+	m.RunFinished(errors.New("boom"))
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after two failures, got %d", code)
+	}
+
+	m.RunStarted()
+	m.RunFinished(nil)
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected 200 after a successful run, got %d", code)
+	}
+}
+
+func TestMonitor_Readyz(t *testing.T) {
+	m := NewMonitor(3, time.Minute, time.Second)
+	smtpErr := errors.New("connection refused")
+	m.AddCheck("github", func(ctx context.Context) error { return nil })
+	m.AddCheck("smtp", func(ctx context.Context) error { return smtpErr })
+	handler := m.Handler()
+
+	get := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	m.RunStarted()
+	m.RunFinished(nil)
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with failing SMTP check, got %d", code)
+	}
+
+	smtpErr = nil
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected 200 with passing checks, got %d", code)
+	}
+}
+
+func TestMonitor_StuckRun(t *testing.T) {
+	m := NewMonitor(3, 10*time.Millisecond, time.Second)
+	m.RunStarted()
+	time.Sleep(20 * time.Millisecond)
+	if m.Alive() {
+		t.Error("Expected monitor to report a stuck run as not alive")
+	}
+}
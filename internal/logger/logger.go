@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type LogLevel int
@@ -16,55 +20,219 @@ const (
 	VERBOSE
 )
 
+// levelVerbose sits below slog.LevelDebug so that verbose output can be
+// filtered independently of debug output
+const levelVerbose = slog.Level(-8)
+
+// Structured field names shared across packages
+const (
+	FieldRepo             = "repo"
+	FieldPR               = "pr"
+	FieldNotificationType = "notification_type"
+	FieldRunID            = "run_id"
+)
+
+// Options configures where and how log records are written
+type Options struct {
+	Level      LogLevel
+	Format     string // "text" or "json"
+	Output     string // "stderr", "stdout" or "file"
+	File       string // Path used when Output is "file"
+	MaxSizeMB  int    // Rotate the log file once it exceeds this size
+	MaxBackups int    // Number of rotated files to keep
+}
+
 type Logger struct {
-	level  LogLevel
-	logger *log.Logger
+	slog     *slog.Logger
+	levelVar *slog.LevelVar // Shared with the loggers made by With; safe to change concurrently
+	progress *progressWriter
+	closer   io.Closer // The log file, nil for stderr and stdout
+}
+
+// progressWriter serialises carriage-return progress output. It is nil when
+// the destination is not a terminal, which suppresses progress entirely.
+type progressWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	active bool
 }
 
 func New(level LogLevel) *Logger {
-	return &Logger{
-		level:  level,
-		logger: log.New(os.Stdout, "", log.LstdFlags),
+	l, _ := NewWithOptions(Options{Level: level})
+	return l
+}
+
+// NewWithOptions creates a logger writing records in the given format to the
+// given output. Progress output is only enabled when writing text to a terminal.
+func NewWithOptions(opts Options) (*Logger, error) {
+	var out io.Writer
+	var closer io.Closer
+	switch strings.ToLower(opts.Output) {
+	case "", "stderr":
+		out = os.Stderr
+	case "stdout":
+		out = os.Stdout
+	case "file":
+		if opts.File == "" {
+			return nil, fmt.Errorf("log output is file but no log file path is configured")
+		}
+		w, err := newRotatingFile(opts.File, opts.MaxSizeMB, opts.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		out, closer = w, w
+	default:
+		return nil, fmt.Errorf("unknown log output %q", opts.Output)
+	}
+
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(toSlogLevel(opts.Level))
+	handlerOpts := &slog.HandlerOptions{
+		Level:       levelVar,
+		ReplaceAttr: replaceLevelName,
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	default:
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	l := &Logger{
+		slog:     slog.New(handler),
+		levelVar: levelVar,
+		closer:   closer,
+	}
+	if f, ok := out.(*os.File); ok && strings.ToLower(opts.Format) != "json" && isTerminal(f) {
+		l.progress = &progressWriter{w: f}
+	}
+	return l, nil
+}
+
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case ERROR:
+		return slog.LevelError
+	case DEBUG:
+		return slog.LevelDebug
+	case VERBOSE:
+		return levelVerbose
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func replaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level <= levelVerbose {
+			a.Value = slog.StringValue("VERBOSE")
+		}
+	}
+	return a
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
+// SetLevel changes the level of the logger and of those made from it by
+// With. It may be called while other goroutines log.
 func (l *Logger) SetLevel(level LogLevel) {
-	l.level = level
+	l.levelVar.Set(toSlogLevel(level))
 }
 
-func (l *Logger) Error(format string, v ...interface{}) {
-	if l.level >= ERROR {
-		l.logger.Printf("[ERROR] "+format, v...)
+// With returns a logger that adds the given key/value pairs to every record
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{
+		slog:     l.slog.With(args...),
+		levelVar: l.levelVar,
+		progress: l.progress,
+		closer:   l.closer,
 	}
 }
 
-func (l *Logger) Info(format string, v ...interface{}) {
-	if l.level >= INFO {
-		l.logger.Printf("[INFO] "+format, v...)
+// Close closes the log file of the logger, if it writes to one. Records
+// logged afterwards through it, or the loggers made from it by With, are
+// dropped.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
 	}
+	return l.closer.Close()
 }
 
-func (l *Logger) Debug(format string, v ...interface{}) {
-	if l.level >= DEBUG {
-		l.logger.Printf("[DEBUG] "+format, v...)
+// Slog exposes the underlying structured logger
+func (l *Logger) Slog() *slog.Logger {
+	return l.slog
+}
+
+func (l *Logger) log(level slog.Level, format string, v ...interface{}) {
+	ctx := context.Background()
+	if !l.slog.Enabled(ctx, level) {
+		return
 	}
+	l.clearProgress()
+	l.slog.Log(ctx, level, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Error(format string, v ...interface{}) {
+	l.log(slog.LevelError, format, v...)
+}
+
+func (l *Logger) Warn(format string, v ...interface{}) {
+	l.log(slog.LevelWarn, format, v...)
+}
+
+func (l *Logger) Info(format string, v ...interface{}) {
+	l.log(slog.LevelInfo, format, v...)
+}
+
+func (l *Logger) Debug(format string, v ...interface{}) {
+	l.log(slog.LevelDebug, format, v...)
 }
 
 func (l *Logger) Verbose(format string, v ...interface{}) {
-	if l.level >= VERBOSE {
-		l.logger.Printf("[VERBOSE] "+format, v...)
-	}
+	l.log(levelVerbose, format, v...)
 }
 
+// Progress rewrites a single status line on the terminal. It is a no-op when
+// logs are not written to a terminal.
 func (l *Logger) Progress(format string, v ...interface{}) {
-	if l.level >= INFO {
-		fmt.Printf("\r[PROGRESS] %-60s", fmt.Sprintf(format, v...))
+	if l.progress == nil || l.levelVar.Level() > slog.LevelInfo {
+		return
 	}
+	l.progress.mu.Lock()
+	defer l.progress.mu.Unlock()
+	fmt.Fprintf(l.progress.w, "\r[PROGRESS] %-60s", fmt.Sprintf(format, v...))
+	l.progress.active = true
 }
 
 func (l *Logger) ProgressEnd() {
-	if l.level >= INFO {
-		fmt.Println()
+	l.clearProgress()
+}
+
+// clearProgress terminates a pending progress line so the next record starts
+// on a fresh line
+func (l *Logger) clearProgress() {
+	if l.progress == nil {
+		return
+	}
+	l.progress.mu.Lock()
+	defer l.progress.mu.Unlock()
+	if l.progress.active {
+		fmt.Fprintln(l.progress.w)
+		l.progress.active = false
 	}
 }
 
@@ -83,17 +251,41 @@ func ParseLogLevel(level string) LogLevel {
 	}
 }
 
-var globalLogger *Logger
+// globalLogger is swapped atomically so that a reload can replace it while
+// other goroutines log
+var globalLogger atomic.Pointer[Logger]
 
 func Init(level LogLevel) {
-	globalLogger = New(level)
+	globalLogger.Store(New(level))
+}
+
+// InitWithOptions replaces the global logger with one built from opts
+func InitWithOptions(opts Options) error {
+	_, err := Replace(opts)
+	return err
+}
+
+// Replace swaps in a global logger built from opts and returns the one it
+// replaced. Loggers made from the old one by With keep using it, so the
+// caller closes it once nothing logs through them anymore.
+func Replace(opts Options) (*Logger, error) {
+	l, err := NewWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return globalLogger.Swap(l), nil
 }
 
 func Get() *Logger {
-	if globalLogger == nil {
-		globalLogger = New(INFO)
+	if l := globalLogger.Load(); l != nil {
+		return l
 	}
-	return globalLogger
+	globalLogger.CompareAndSwap(nil, New(INFO))
+	return globalLogger.Load()
+}
+
+func With(args ...any) *Logger {
+	return Get().With(args...)
 }
 
 func Error(format string, v ...interface{}) {
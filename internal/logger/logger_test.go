@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogger_JSONFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watcher.log")
+	l, err := NewWithOptions(Options{Level: VERBOSE, Format: "json", Output: "file", File: path})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+
+	l.With(FieldRepo, "api", FieldPR, 42).Verbose("checking PR #%d", 42)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("log record is not JSON: %v (%s)", err, data)
+	}
+	if record["level"] != "VERBOSE" {
+		t.Errorf("Expected level VERBOSE, got %v", record["level"])
+	}
+	if record["msg"] != "checking PR #42" {
+		t.Errorf("Expected formatted message, got %v", record["msg"])
+	}
+	if record["repo"] != "api" || record["pr"] != float64(42) {
+		t.Errorf("Expected repo and pr fields, got %v", record)
+	}
+}
+
+func TestLogger_LevelFiltering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watcher.log")
+	l, err := NewWithOptions(Options{Level: INFO, Output: "file", File: path})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+
+	l.Debug("hidden")
+	l.Info("shown")
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "hidden") {
+		t.Error("Debug record should be filtered at INFO level")
+	}
+	if !strings.Contains(string(data), "shown") {
+		t.Error("Info record should be written at INFO level")
+	}
+}
+
+func TestRotatingFile_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watcher.log")
+	r, err := newRotatingFile(path, 1, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile failed: %v", err)
+	}
+	r.maxSize = 10
+
+	for i := 0; i < 4; i++ {
+		if _, err := r.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected at most 2 backups, found %s.3", path)
+	}
+}
+
+func TestLogger_SetLevelWhileLogging(t *testing.T) {
+	l, err := NewWithOptions(Options{Level: INFO, Output: "file", File: filepath.Join(t.TempDir(), "watcher.log")})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	child := l.With(FieldRepo, "api")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			child.Debug("record %d", i)
+			child.Progress("record %d", i)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		l.SetLevel(LogLevel(i % 4))
+	}
+	<-done
+
+	l.SetLevel(ERROR)
+	if child.Slog().Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("Expected SetLevel to apply to loggers made by With")
+	}
+}
@@ -0,0 +1,121 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+)
+
+// CommentMarker identifies the reminder comment the watcher keeps on a pull
+// request, so that later reminders edit it instead of adding new comments
+const CommentMarker = forge.MarkerPrefix + "reminder -->"
+
+// ReminderComment renders the Markdown body of the reminder comment for a
+// notification. threshold is the one the PR passed, its age or for the
+// waiting reminders how long it has waited. The body leaves out the running
+// age so that it only changes, and the comment is only edited, when the
+// state of the PR does. mentions are the users or teams who need to act.
+func ReminderComment(typ NotificationType, pr *github.PullRequest, threshold time.Duration, mentions []string) string {
+	var title, action string
+	switch typ {
+	case ApprovalReminder:
+		title = "This pull request needs approval"
+		action = "please review it and approve it if it is ready."
+	case MergeReminder:
+		title = "This pull request is approved and waiting to be merged"
+		action = "please merge it, or say what is still blocking it."
+	case Escalation:
+		title = "This pull request has exceeded the merge time"
+		action = "please get it reviewed and merged, or close it if it is no longer needed."
+	case DraftOverdue:
+		title = "This draft pull request is overdue"
+		action = "please mark it as ready for review, or close it if it is no longer needed."
+	case WaitingOnReviewer:
+		title = "This pull request is waiting on review"
+		action = "please review it, or hand it to someone who can."
+	case WaitingOnAuthor:
+		title = "This pull request is waiting on its author"
+		action = "please address the last review, or close it if it is no longer needed."
+	case CIFailing:
+		title = "This pull request is approved but its checks fail"
+		action = "please fix " + strings.Join(pr.FailingChecks(), ", ") + " so it can be merged."
+	default:
+		title = "This pull request needs attention"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n### %s\n\n", CommentMarker, title)
+	if typ == WaitingOnReviewer || typ == WaitingOnAuthor {
+		fmt.Fprintf(&b, "Waiting for more than **%s**, with %d approving review(s).\n\n",
+			formatDuration(threshold), pr.ReviewCount)
+	} else {
+		fmt.Fprintf(&b, "Open for more than **%s**, with %d approving review(s).\n\n",
+			formatDuration(threshold), pr.ReviewCount)
+	}
+	if len(mentions) > 0 {
+		fmt.Fprintf(&b, "**Waiting on:** %s: %s\n\n", mentionList(mentions), action)
+	} else if action != "" {
+		fmt.Fprintf(&b, "**Action required:** %s\n\n", strings.ToUpper(action[:1])+action[1:])
+	}
+	b.WriteString("<sub>Kept up to date by the PR Age Watcher. This comment is edited in place as the pull request progresses.</sub>\n")
+	return b.String()
+}
+
+// mentionList formats users and teams as @mentions, without duplicates
+func mentionList(mentions []string) string {
+	seen := make(map[string]bool, len(mentions))
+	var list []string
+	for _, m := range mentions {
+		m = strings.TrimPrefix(m, "@")
+		key := strings.ToLower(m)
+		if m == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		list = append(list, "@"+m)
+	}
+	return strings.Join(list, " ")
+}
+
+// StaleDraftComment renders the warning left on a draft without activity
+// for idle. closeAt is when it will be closed, zero if it will not be.
+func StaleDraftComment(pr *github.PullRequest, idle time.Duration, closeAt time.Time, exemptLabel string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n### This draft pull request looks abandoned\n\n", CommentMarker)
+	fmt.Fprintf(&b, "There has been no push or comment for **%s**.", formatDuration(idle))
+	if !closeAt.IsZero() {
+		fmt.Fprintf(&b, " It will be closed after %s unless there is new activity.", closeAt.UTC().Format("2006-01-02 15:04 MST"))
+	}
+	b.WriteString("\n\n")
+	if pr.User != nil && pr.User.Login != "" {
+		fmt.Fprintf(&b, "@%s, please push your latest work, mark it as ready for review, or close it if it is no longer needed. ", pr.User.Login)
+	}
+	fmt.Fprintf(&b, "Add the `%s` label to keep it open.\n", exemptLabel)
+	return b.String()
+}
+
+// ClosedDraftComment renders the explanation left on a draft the watcher
+// closed
+func ClosedDraftComment(pr *github.PullRequest, idle time.Duration, exemptLabel string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n### This draft pull request was closed\n\n", CommentMarker)
+	fmt.Fprintf(&b, "It was closed automatically after **%s** without a push or comment.", formatDuration(idle))
+	if pr.Head != nil && pr.Head.Ref != "" {
+		fmt.Fprintf(&b, " No work is lost: the branch `%s` is untouched.", pr.Head.Ref)
+	}
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "To pick it up again, reopen the pull request and push to its branch. Add the `%s` label after reopening so it is not closed again.\n", exemptLabel)
+	return b.String()
+}
+
+// SnoozedComment renders the reminder comment while reminders are snoozed
+func SnoozedComment(until time.Time, by string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n### Reminders snoozed\n\n", CommentMarker)
+	fmt.Fprintf(&b, "Snoozed by %s until %s. A new push or review ends the snooze early; comment `/pr-watcher unsnooze` to end it now.\n",
+		mentionList([]string{by}), until.UTC().Format("2006-01-02 15:04 MST"))
+	return b.String()
+}
@@ -1,15 +1,22 @@
 package notifier
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"html/template"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
 	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
 	"gopkg.in/gomail.v2"
 )
 
@@ -51,7 +58,7 @@ func getEmailTemplate() *template.Template {
         <div class="pr-info">
             <div class="pr-title">{{.PullRequest.Title}}</div>
             <div class="pr-details">
-                <strong>Repository:</strong> {{.PullRequest.Repo}}<br>
+                <strong>Repository:</strong> {{.PullRequest.FullName}}<br>
                 <strong>Author:</strong> {{.PullRequest.User.Login}}<br>
                 <strong>Branch:</strong> {{.PullRequest.Head.Ref}} → {{.PullRequest.Base.Ref}}<br>
                 <strong>Created:</strong> {{.PullRequest.CreatedAt.Format "2006-01-02 15:04:05"}}<br>
@@ -61,8 +68,10 @@ func getEmailTemplate() *template.Template {
         </div>
 
         <div class="age-info">
-            <strong>Age:</strong> {{.AgeText}}<br>
-            <strong>Threshold:</strong> {{.ThresholdText}}
+            <strong>{{.AgeLabel}}:</strong> {{.AgeText}}<br>
+            <strong>Threshold:</strong> {{.ThresholdText}}<br>
+            <strong>Waiting on:</strong> {{.WaitingOn}}{{range .Activity}}<br>
+            <strong>{{.Label}}:</strong> {{.Value}}{{end}}
         </div>
 
         {{if .ActionRequired}}
@@ -75,6 +84,11 @@ func getEmailTemplate() *template.Template {
         <div style="text-align: center;">
             <a href="{{.PullRequest.URL}}" class="button">View Pull Request</a>
         </div>
+        {{if .SnoozeLinks}}
+        <p style="text-align: center; font-size: 14px;">
+            Already on it? Snooze reminders for{{range $i, $link := .SnoozeLinks}}{{if $i}} ·{{end}} <a href="{{$link.URL}}">{{$link.Label}}</a>{{end}}
+        </p>
+        {{end}}
 
         <div class="footer">
             <p>This is an automated message from the PR Age Watcher.</p>
@@ -118,6 +132,9 @@ const (
 	MergeReminder
 	Escalation
 	DraftOverdue
+	WaitingOnReviewer
+	WaitingOnAuthor
+	CIFailing
 )
 
 type NotificationData struct {
@@ -126,33 +143,43 @@ type NotificationData struct {
 	Age         time.Duration
 	Threshold   time.Duration
 	Recipients  []string
+	SnoozeLinks []SnoozeLink
+}
+
+// SnoozeLink is a signed link in an email that snoozes the reminders of its
+// pull request for Duration
+type SnoozeLink struct {
+	Duration time.Duration
+	URL      string
 }
 
-func (e *EmailNotifier) SendApprovalReminder(pr *github.PullRequest, age time.Duration, threshold time.Duration) error {
+func (e *EmailNotifier) SendApprovalReminder(ctx context.Context, pr *github.PullRequest, age time.Duration, threshold time.Duration, snoozeLinks ...SnoozeLink) error {
 	data := &NotificationData{
 		Type:        ApprovalReminder,
 		PullRequest: pr,
 		Age:         age,
 		Threshold:   threshold,
-		Recipients:  e.config.To,
+		Recipients:  e.waitingOn(pr),
+		SnoozeLinks: snoozeLinks,
 	}
 
-	return e.sendNotification(data)
+	return e.sendNotification(ctx, data)
 }
 
-func (e *EmailNotifier) SendMergeReminder(pr *github.PullRequest, age time.Duration, threshold time.Duration) error {
+func (e *EmailNotifier) SendMergeReminder(ctx context.Context, pr *github.PullRequest, age time.Duration, threshold time.Duration, snoozeLinks ...SnoozeLink) error {
 	data := &NotificationData{
 		Type:        MergeReminder,
 		PullRequest: pr,
 		Age:         age,
 		Threshold:   threshold,
-		Recipients:  e.config.To,
+		Recipients:  e.waitingOn(pr),
+		SnoozeLinks: snoozeLinks,
 	}
 
-	return e.sendNotification(data)
+	return e.sendNotification(ctx, data)
 }
 
-func (e *EmailNotifier) SendEscalation(pr *github.PullRequest, age time.Duration, threshold time.Duration, escalationEmail string) error {
+func (e *EmailNotifier) SendEscalation(ctx context.Context, pr *github.PullRequest, age time.Duration, threshold time.Duration, escalationEmail string, snoozeLinks ...SnoozeLink) error {
 	recipients := e.config.To
 	if escalationEmail != "" {
 		recipients = append(recipients, escalationEmail)
@@ -164,24 +191,115 @@ func (e *EmailNotifier) SendEscalation(pr *github.PullRequest, age time.Duration
 		Age:         age,
 		Threshold:   threshold,
 		Recipients:  recipients,
+		SnoozeLinks: snoozeLinks,
 	}
 
-	return e.sendNotification(data)
+	return e.sendNotification(ctx, data)
 }
 
-func (e *EmailNotifier) SendDraftOverdue(pr *github.PullRequest, age time.Duration, threshold time.Duration) error {
+func (e *EmailNotifier) SendDraftOverdue(ctx context.Context, pr *github.PullRequest, age time.Duration, threshold time.Duration, snoozeLinks ...SnoozeLink) error {
 	data := &NotificationData{
 		Type:        DraftOverdue,
 		PullRequest: pr,
 		Age:         age,
 		Threshold:   threshold,
-		Recipients:  e.config.To,
+		Recipients:  e.waitingOn(pr),
+		SnoozeLinks: snoozeLinks,
+	}
+
+	return e.sendNotification(ctx, data)
+}
+
+// SendWaitingOnReviewer reminds reviewers that pr has waited on them for
+// waited. They are emailed at their email.users addresses, or email.to if
+// none is known.
+func (e *EmailNotifier) SendWaitingOnReviewer(ctx context.Context, pr *github.PullRequest, waited time.Duration, threshold time.Duration, reviewers []string, snoozeLinks ...SnoozeLink) error {
+	data := &NotificationData{
+		Type:        WaitingOnReviewer,
+		PullRequest: pr,
+		Age:         waited,
+		Threshold:   threshold,
+		Recipients:  e.recipients(reviewers),
+		SnoozeLinks: snoozeLinks,
 	}
 
-	return e.sendNotification(data)
+	return e.sendNotification(ctx, data)
+}
+
+// SendWaitingOnAuthor reminds the author that pr has waited on them for
+// waited. They are emailed at the address the forge returned or their
+// email.users address, or email.to if neither is known.
+func (e *EmailNotifier) SendWaitingOnAuthor(ctx context.Context, pr *github.PullRequest, waited time.Duration, threshold time.Duration, snoozeLinks ...SnoozeLink) error {
+	data := &NotificationData{
+		Type:        WaitingOnAuthor,
+		PullRequest: pr,
+		Age:         waited,
+		Threshold:   threshold,
+		Recipients:  e.author(pr),
+		SnoozeLinks: snoozeLinks,
+	}
+
+	return e.sendNotification(ctx, data)
+}
+
+// SendCIFailing asks the author of an approved pr to fix its failing checks
+// instead of reminding anyone to merge it. They are emailed like for
+// SendWaitingOnAuthor.
+func (e *EmailNotifier) SendCIFailing(ctx context.Context, pr *github.PullRequest, age time.Duration, threshold time.Duration, snoozeLinks ...SnoozeLink) error {
+	data := &NotificationData{
+		Type:        CIFailing,
+		PullRequest: pr,
+		Age:         age,
+		Threshold:   threshold,
+		Recipients:  e.author(pr),
+		SnoozeLinks: snoozeLinks,
+	}
+
+	return e.sendNotification(ctx, data)
+}
+
+// waitingOn returns the addresses of whoever pr is waiting on when
+// email.notify_waiting_on is set, and email.to otherwise
+func (e *EmailNotifier) waitingOn(pr *github.PullRequest) []string {
+	if !e.config.NotifyWaitingOn {
+		return e.config.To
+	}
+	if who, _ := pr.BallInCourt(); who == forge.WaitingOnReviewer {
+		return e.recipients(pr.Reviewers())
+	}
+	return e.author(pr)
+}
+
+// author returns the address of the author of pr the forge returned, or
+// their email.users address, or email.to if neither is known
+func (e *EmailNotifier) author(pr *github.PullRequest) []string {
+	if pr.User == nil {
+		return e.config.To
+	}
+	if pr.User.Email != "" {
+		return []string{pr.User.Email}
+	}
+	return e.recipients([]string{pr.User.Login})
+}
+
+// recipients returns the email.users addresses of logins, or email.to if
+// none of them has one. Logins are matched ignoring case.
+func (e *EmailNotifier) recipients(logins []string) []string {
+	var addresses []string
+	for _, login := range logins {
+		for user, address := range e.config.Users {
+			if strings.EqualFold(user, login) && address != "" && !slices.Contains(addresses, address) {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return e.config.To
+	}
+	return addresses
 }
 
-func (e *EmailNotifier) sendNotification(data *NotificationData) error {
+func (e *EmailNotifier) sendNotification(ctx context.Context, data *NotificationData) error {
 	e.mu.Lock()
 	if e.closed {
 		e.mu.Unlock()
@@ -189,8 +307,14 @@ func (e *EmailNotifier) sendNotification(data *NotificationData) error {
 	}
 	e.mu.Unlock()
 
+	log := logger.With(
+		logger.FieldRepo, data.PullRequest.FullName(),
+		logger.FieldPR, data.PullRequest.Number,
+		logger.FieldNotificationType, getNotificationTypeName(data.Type),
+	)
+
 	if e.skipEmails {
-		fmt.Printf("[SKIPPED] Would send %s email for PR #%d to %v\n",
+		log.Info("[SKIPPED] Would send %s email for PR #%d to %v",
 			getNotificationTypeName(data.Type), data.PullRequest.Number, data.Recipients)
 		return nil
 	}
@@ -200,8 +324,11 @@ func (e *EmailNotifier) sendNotification(data *NotificationData) error {
 	timeSinceLastSent := time.Since(e.lastSent)
 	if timeSinceLastSent < e.rateLimit {
 		waitTime := e.rateLimit - timeSinceLastSent
-		fmt.Printf("[DEBUG] Rate limiting: waiting %v before sending next email\n", waitTime)
-		time.Sleep(waitTime)
+		log.Debug("Rate limiting: waiting %v before sending next email", waitTime)
+		if err := sleep(ctx, waitTime); err != nil {
+			e.mu.Unlock()
+			return err
+		}
 	}
 	e.lastSent = time.Now()
 	e.mu.Unlock()
@@ -232,27 +359,88 @@ func (e *EmailNotifier) sendNotification(data *NotificationData) error {
 			}
 		}
 
-		fmt.Printf("[DEBUG] Attempting SMTP connection to %s:%d (attempt %d/%d)\n",
+		log.Debug("Attempting SMTP connection to %s:%d (attempt %d/%d)",
 			e.config.SMTPHost, e.config.SMTPPort, i+1, maxRetries)
 
-		if err := dialer.DialAndSend(m); err != nil {
-			fmt.Printf("[DEBUG] SMTP connection failed: %v\n", err)
+		if err := e.send(ctx, dialer, m); err != nil {
+			// An abandoned attempt may still deliver the email, so retrying
+			// it could send a duplicate
+			if ctx.Err() != nil || errors.Is(err, errAbandoned) {
+				return fmt.Errorf("failed to send email: %w", err)
+			}
+			log.Debug("SMTP connection failed: %v", err)
 			if i == maxRetries-1 {
 				return fmt.Errorf("failed to send email after %d retries: %w", maxRetries, err)
 			}
 
 			delay := baseDelay * time.Duration(1<<uint(i))
-			fmt.Printf("Email send attempt %d failed (%v), retrying in %v...\n", i+1, err, delay)
-			time.Sleep(delay)
+			log.Info("Email send attempt %d failed (%v), retrying in %v...", i+1, err, delay)
+			if err := sleep(ctx, delay); err != nil {
+				return fmt.Errorf("failed to send email: %w", err)
+			}
 			continue
 		}
-		fmt.Printf("[DEBUG] SMTP connection successful\n")
+		log.Debug("SMTP connection successful")
 		break
 	}
 
 	return nil
 }
 
+// errAbandoned is returned for an attempt that timed out or was canceled
+// while it may still be delivering the email
+var errAbandoned = errors.New("email delivery abandoned")
+
+// send delivers m within email.timeout. gomail cannot be interrupted, so on
+// timeout or cancellation the attempt is abandoned and finishes in the
+// background, and errAbandoned is returned.
+func (e *EmailNotifier) send(ctx context.Context, dialer *gomail.Dialer, m *gomail.Message) error {
+	if e.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- dialer.DialAndSend(m)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", errAbandoned, ctx.Err())
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ping checks that the SMTP server accepts TCP connections. It does not
+// authenticate or send anything, and always succeeds when emails are skipped.
+func (e *EmailNotifier) Ping(ctx context.Context) error {
+	if e.skipEmails {
+		return nil
+	}
+
+	addr := net.JoinHostPort(e.config.SMTPHost, strconv.Itoa(e.config.SMTPPort))
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	return conn.Close()
+}
+
 func (e *EmailNotifier) Close() {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -264,6 +452,10 @@ func (e *EmailNotifier) Close() {
 	e.closed = true
 }
 
+func (nt NotificationType) String() string {
+	return getNotificationTypeName(nt)
+}
+
 func getNotificationTypeName(nt NotificationType) string {
 	switch nt {
 	case ApprovalReminder:
@@ -274,6 +466,12 @@ func getNotificationTypeName(nt NotificationType) string {
 		return "escalation"
 	case DraftOverdue:
 		return "draft overdue"
+	case WaitingOnReviewer:
+		return "waiting on reviewer"
+	case WaitingOnAuthor:
+		return "waiting on author"
+	case CIFailing:
+		return "ci failing"
 	default:
 		return "unknown"
 	}
@@ -294,6 +492,12 @@ func (e *EmailNotifier) getSubject(data *NotificationData) string {
 		return fmt.Sprintf("ESCALATION: %s - PR #%d exceeds merge time (%s)", baseSubject, data.PullRequest.Number, data.PullRequest.Repo)
 	case DraftOverdue:
 		return fmt.Sprintf("DRAFT OVERDUE: %s - Draft PR #%d needs attention (%s)", baseSubject, data.PullRequest.Number, data.PullRequest.Repo)
+	case WaitingOnReviewer:
+		return fmt.Sprintf("%s - PR #%d is waiting on review (%s)", baseSubject, data.PullRequest.Number, data.PullRequest.Repo)
+	case WaitingOnAuthor:
+		return fmt.Sprintf("%s - PR #%d is waiting on its author (%s)", baseSubject, data.PullRequest.Number, data.PullRequest.Repo)
+	case CIFailing:
+		return fmt.Sprintf("CI FAILING: %s - Approved PR #%d fails its checks (%s)", baseSubject, data.PullRequest.Number, data.PullRequest.Repo)
 	default:
 		return baseSubject
 	}
@@ -306,19 +510,43 @@ func (e *EmailNotifier) generateEmailBody(data *NotificationData) (string, error
 		HeaderColor    string
 		AgeColor       string
 		PullRequest    *github.PullRequest
+		AgeLabel       string
 		AgeText        string
 		ThresholdText  string
+		WaitingOn      string
+		Activity       []struct{ Label, Value string }
 		ActionRequired bool
 		ActionText     string
+		SnoozeLinks    []struct{ Label, URL string }
 		GeneratedAt    string
 	}
 
 	templateData := TemplateData{
 		PullRequest:   data.PullRequest,
+		AgeLabel:      "Age",
 		AgeText:       formatDuration(data.Age),
 		ThresholdText: formatDuration(data.Threshold),
 		GeneratedAt:   time.Now().Format("2006-01-02 15:04:05"),
 	}
+	who, since := data.PullRequest.BallInCourt()
+	templateData.WaitingOn = waitingOnLabel(who) + " for " + formatDuration(time.Since(since))
+	activity := data.PullRequest.Activity()
+	for _, a := range []struct {
+		label string
+		at    time.Time
+	}{
+		{"Last push", activity.LastPush},
+		{"Last review", activity.LastReview},
+		{"Last comment", activity.LastComment},
+		{"Author's last response", activity.LastAuthorResponse},
+	} {
+		if !a.at.IsZero() {
+			templateData.Activity = append(templateData.Activity, struct{ Label, Value string }{a.label, formatDuration(time.Since(a.at)) + " ago"})
+		}
+	}
+	for _, link := range data.SnoozeLinks {
+		templateData.SnoozeLinks = append(templateData.SnoozeLinks, struct{ Label, URL string }{formatDuration(link.Duration), link.URL})
+	}
 
 	switch data.Type {
 	case ApprovalReminder:
@@ -345,6 +573,26 @@ func (e *EmailNotifier) generateEmailBody(data *NotificationData) (string, error
 		templateData.AgeColor = "#e9ecef"
 		templateData.ActionRequired = true
 		templateData.ActionText = "This draft pull request has been open for " + formatDuration(data.Age) + " and exceeds the draft time threshold of " + formatDuration(data.Threshold) + ". Please either mark as ready for review or close if no longer needed."
+	case WaitingOnReviewer:
+		templateData.Title = "PR Waiting on Review"
+		templateData.HeaderColor = "#fd7e14"
+		templateData.AgeColor = "#ffe5d0"
+		templateData.AgeLabel = "Waiting on reviewers"
+		templateData.ActionRequired = true
+		templateData.ActionText = "This pull request has been waiting on its reviewers for " + formatDuration(data.Age) + ". Please review it, or hand it to someone who can."
+	case WaitingOnAuthor:
+		templateData.Title = "PR Waiting on Author"
+		templateData.HeaderColor = "#17a2b8"
+		templateData.AgeColor = "#d1ecf1"
+		templateData.AgeLabel = "Waiting on the author"
+		templateData.ActionRequired = true
+		templateData.ActionText = "This pull request has been waiting on its author for " + formatDuration(data.Age) + " since the last review. Please address the review, or close the pull request if it is no longer needed."
+	case CIFailing:
+		templateData.Title = "Approved PR Failing Checks"
+		templateData.HeaderColor = "#dc3545"
+		templateData.AgeColor = "#f8d7da"
+		templateData.ActionRequired = true
+		templateData.ActionText = "This pull request is approved but cannot be merged while these checks fail: " + strings.Join(data.PullRequest.FailingChecks(), ", ") + ". Please fix them."
 	}
 
 	// Use singleton template
@@ -357,6 +605,22 @@ func (e *EmailNotifier) generateEmailBody(data *NotificationData) (string, error
 	return buf.String(), nil
 }
 
+// waitingOnLabel describes whose turn it is, as returned by BallInCourt
+func waitingOnLabel(who string) string {
+	switch who {
+	case forge.WaitingOnReviewer:
+		return "Reviewers"
+	case forge.WaitingOnAuthor:
+		return "Author"
+	case forge.WaitingOnCI:
+		return "CI (checks running)"
+	case forge.WaitingOnMerge:
+		return "Merge (approved)"
+	default:
+		return who
+	}
+}
+
 func formatDuration(d time.Duration) string {
 	if d < time.Hour {
 		return fmt.Sprintf("%.0f minutes", d.Minutes())
@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+)
+
+func TestSendNotification_TimedOutAttemptIsNotRetried(t *testing.T) {
+	// An SMTP server that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	var connections atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	e, err := NewEmailNotifier(config.EmailConfig{
+		SMTPHost: host,
+		SMTPPort: portNumber,
+		From:     "watcher@example.com",
+		To:       []string{"team@example.com"},
+		Timeout:  100 * time.Millisecond,
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr := &github.PullRequest{Number: 1, Repo: "api", User: &github.User{Login: "alice"}, Head: &github.Branch{}, Base: &github.Branch{}}
+	start := time.Now()
+	err = e.SendApprovalReminder(context.Background(), pr, time.Hour, time.Hour)
+	if !errors.Is(err, errAbandoned) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected an abandoned attempt, got %v", err)
+	}
+	// A retry would first wait two seconds
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected no retry after the timeout, took %v", elapsed)
+	}
+	if n := connections.Load(); n != 1 {
+		t.Errorf("Expected a single delivery attempt, got %d", n)
+	}
+}
@@ -0,0 +1,225 @@
+package schedule
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the next activation time strictly after the given time.
+// A zero time means the schedule never fires again.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Interval fires every Every, optionally restricted to a daily window
+type Interval struct {
+	Every  time.Duration
+	Window *Window
+}
+
+// Window restricts activations to a time-of-day range on selected weekdays
+type Window struct {
+	Start    int // Minutes after midnight, inclusive
+	End      int // Minutes after midnight, exclusive
+	Days     [7]bool
+	Location *time.Location
+}
+
+func (s *Interval) Next(after time.Time) time.Time {
+	next := after.Add(s.Every)
+	if s.Window == nil {
+		return next
+	}
+	return s.Window.nextOpen(next)
+}
+
+// Contains reports whether t falls inside the window
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.Location)
+	if !w.Days[t.Weekday()] {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	return minute >= w.Start && minute < w.End
+}
+
+// nextOpen returns t if it is inside the window, otherwise the start of the
+// next window opening
+func (w *Window) nextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	local := t.In(w.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.Location)
+	for i := 0; i < 8; i++ {
+		start := day.Add(time.Duration(w.Start) * time.Minute)
+		if w.Days[day.Weekday()] && start.After(t) {
+			return start
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// ParseWindow parses an active window from a "HH:MM-HH:MM" range and a list
+// of weekdays ("Mon-Fri", "Sat", ...). An empty day list means every day.
+func ParseWindow(hours string, days []string, loc *time.Location) (*Window, error) {
+	w := &Window{Start: 0, End: 24 * 60, Location: loc}
+
+	if hours != "" {
+		from, to, ok := strings.Cut(hours, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid active hours %q: expected HH:MM-HH:MM", hours)
+		}
+		start, err := parseClock(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid active hours %q: %w", hours, err)
+		}
+		end, err := parseClock(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid active hours %q: %w", hours, err)
+		}
+		if end <= start {
+			return nil, fmt.Errorf("invalid active hours %q: end must be after start", hours)
+		}
+		w.Start, w.End = start, end
+	}
+
+	if len(days) == 0 {
+		for i := range w.Days {
+			w.Days[i] = true
+		}
+		return w, nil
+	}
+	for _, d := range days {
+		from, to, isRange := strings.Cut(d, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return nil, err
+			}
+		}
+		for i := first; ; i = (i + 1) % 7 {
+			w.Days[i] = true
+			if i == last {
+				break
+			}
+		}
+	}
+	return w, nil
+}
+
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hour, err := strconv.Atoi(h)
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("invalid hour in %q", s)
+	}
+	minute, err := strconv.Atoi(m)
+	if err != nil || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid minute in %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+var weekdays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+func parseWeekday(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) >= 3 {
+		if d, ok := weekdays[s[:3]]; ok {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+// Options describes a schedule as configured in the rules section
+type Options struct {
+	Cron        string        // Cron expression; takes precedence over Interval
+	Interval    time.Duration // Fixed interval between runs
+	ActiveHours string        // Optional "HH:MM-HH:MM" window
+	ActiveDays  []string      // Optional weekdays, e.g. ["Mon-Fri"]
+	Timezone    string        // IANA zone for cron and window evaluation
+}
+
+// New builds a schedule from opts
+func New(opts Options) (Schedule, error) {
+	loc := time.Local
+	if opts.Timezone != "" {
+		l, err := time.LoadLocation(opts.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", opts.Timezone, err)
+		}
+		loc = l
+	}
+
+	var window *Window
+	if opts.ActiveHours != "" || len(opts.ActiveDays) > 0 {
+		w, err := ParseWindow(opts.ActiveHours, opts.ActiveDays, loc)
+		if err != nil {
+			return nil, err
+		}
+		window = w
+	}
+
+	if opts.Cron != "" {
+		c, err := ParseCron(opts.Cron, loc)
+		if err != nil {
+			return nil, err
+		}
+		if window == nil {
+			return c, nil
+		}
+		return &windowed{schedule: c, window: window}, nil
+	}
+
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("check interval must be positive, got %v", opts.Interval)
+	}
+	return &Interval{Every: opts.Interval, Window: window}, nil
+}
+
+// windowed filters the activations of another schedule through a window
+type windowed struct {
+	schedule Schedule
+	window   *Window
+}
+
+func (s *windowed) Next(after time.Time) time.Time {
+	t := after
+	for i := 0; i < 100000; i++ {
+		t = s.schedule.Next(t)
+		if t.IsZero() || s.window.Contains(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// IsContinuous reports whether s is a plain interval that may fire
+// immediately at startup without violating a cron expression or window
+func IsContinuous(s Schedule) bool {
+	i, ok := s.(*Interval)
+	return ok && i.Window == nil
+}
+
+// Jitter returns a random duration in [0, max)
+func Jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(max)))
+}
@@ -0,0 +1,88 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCron_Next(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		name     string
+		spec     string
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "every 30 minutes during office hours",
+			spec:     "*/30 8-17 * * Mon-Fri",
+			after:    time.Date(2026, 10, 16, 17, 45, 0, 0, utc), // Friday
+			expected: time.Date(2026, 10, 19, 8, 0, 0, 0, utc),   // Monday
+		},
+		{
+			name:     "hourly alias",
+			spec:     "@hourly",
+			after:    time.Date(2026, 10, 16, 10, 15, 0, 0, utc),
+			expected: time.Date(2026, 10, 16, 11, 0, 0, 0, utc),
+		},
+		{
+			name:     "day of month or day of week",
+			spec:     "0 9 1 * 0",
+			after:    time.Date(2026, 10, 16, 10, 0, 0, 0, utc),
+			expected: time.Date(2026, 10, 18, 9, 0, 0, 0, utc), // Sunday
+		},
+		{
+			name:     "timezone prefix",
+			spec:     "CRON_TZ=America/New_York 0 9 * * *",
+			after:    time.Date(2026, 10, 16, 12, 0, 0, 0, utc),
+			expected: time.Date(2026, 10, 16, 13, 0, 0, 0, utc),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.spec, utc)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) failed: %v", tt.spec, err)
+			}
+			if got := c.Next(tt.after); !got.Equal(tt.expected) {
+				t.Errorf("Next(%v) = %v, expected %v", tt.after, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * * * Funday", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(spec, time.UTC); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestInterval_Window(t *testing.T) {
+	s, err := New(Options{
+		Interval:    30 * time.Minute,
+		ActiveHours: "08:00-18:00",
+		ActiveDays:  []string{"Mon-Fri"},
+		Timezone:    "UTC",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	inside := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC) // Wednesday
+	if got := s.Next(inside); !got.Equal(inside.Add(30 * time.Minute)) {
+		t.Errorf("Expected next run 30m later inside the window, got %v", got)
+	}
+
+	evening := time.Date(2026, 10, 16, 17, 50, 0, 0, time.UTC) // Friday
+	expected := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	if got := s.Next(evening); !got.Equal(expected) {
+		t.Errorf("Expected next run at window opening %v, got %v", expected, got)
+	}
+
+	if IsContinuous(s) {
+		t.Error("Windowed interval should not be continuous")
+	}
+}
@@ -0,0 +1,78 @@
+package snooze
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+)
+
+// Command is a /pr-watcher comment command
+type Command struct {
+	Action   string        // snooze or unsnooze; ack is a snooze for the default duration
+	Duration time.Duration // How long to snooze
+}
+
+// ParseCommand parses a command line such as "/pr-watcher snooze 2d".
+// Snoozes without a duration, and acks, last def.
+func ParseCommand(line string, def time.Duration) (Command, error) {
+	fields := strings.Fields(strings.TrimSpace(strings.TrimPrefix(line, forge.CommandPrefix)))
+	if len(fields) == 0 {
+		return Command{}, fmt.Errorf("missing command, expected snooze, ack or unsnooze")
+	}
+	switch action := strings.ToLower(fields[0]); action {
+	case "snooze":
+		if len(fields) > 2 {
+			return Command{}, fmt.Errorf("snooze takes a single duration such as 2d, got %q", strings.Join(fields[1:], " "))
+		}
+		cmd := Command{Action: "snooze", Duration: def}
+		if len(fields) == 2 {
+			d, err := ParseDuration(fields[1])
+			if err != nil {
+				return Command{}, err
+			}
+			cmd.Duration = d
+		}
+		return cmd, nil
+	case "ack", "unsnooze":
+		if len(fields) > 1 {
+			return Command{}, fmt.Errorf("%s takes no arguments", action)
+		}
+		if action == "ack" {
+			return Command{Action: "snooze", Duration: def}, nil
+		}
+		return Command{Action: "unsnooze"}, nil
+	default:
+		return Command{}, fmt.Errorf("unknown command %q, expected snooze, ack or unsnooze", fields[0])
+	}
+}
+
+// ParseDuration parses a positive duration in days ("2d"), weeks ("1w") or
+// anything time.ParseDuration accepts ("36h")
+func ParseDuration(s string) (time.Duration, error) {
+	var (
+		d   time.Duration
+		err error
+	)
+	switch {
+	case strings.HasSuffix(s, "d"), strings.HasSuffix(s, "w"):
+		unit := 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			unit *= 7
+		}
+		var n int
+		n, err = strconv.Atoi(s[:len(s)-1])
+		d = time.Duration(n) * unit
+	default:
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected e.g. 2d, 1w or 12h", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,109 @@
+package snooze
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+)
+
+// Link returns the URL of a link that snoozes the pull request key for d,
+// signed with secret and working until expires
+func Link(baseURL, secret, key string, d time.Duration, expires time.Time) string {
+	q := url.Values{}
+	q.Set("pr", key)
+	q.Set("for", d.String())
+	q.Set("exp", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("sig", sign(secret, q))
+	return baseURL + "?" + q.Encode()
+}
+
+func sign(secret string, q url.Values) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(q.Get("pr") + "\n" + q.Get("for") + "\n" + q.Get("exp")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature and expiry of a link and returns the key and
+// duration it snoozes
+func verify(secret string, q url.Values, now time.Time) (string, time.Duration, error) {
+	if !hmac.Equal([]byte(sign(secret, q)), []byte(q.Get("sig"))) {
+		return "", 0, errors.New("invalid signature")
+	}
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil {
+		return "", 0, errors.New("invalid expiry")
+	}
+	if now.After(time.Unix(exp, 0)) {
+		return "", 0, errors.New("link expired")
+	}
+	d, err := time.ParseDuration(q.Get("for"))
+	if err != nil || d <= 0 {
+		return "", 0, errors.New("invalid duration")
+	}
+	return q.Get("pr"), d, nil
+}
+
+var page = template.Must(template.New("snooze").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Snooze reminders</title></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 40px auto; color: #333;">
+{{if .Error}}<p>This snooze link cannot be used: {{.Error}}.</p>
+{{else if .Until}}<p>Reminders for <strong>{{.PR}}</strong> are snoozed until {{.Until}}. A new push or review ends the snooze early.</p>
+{{else}}<form method="POST">
+<p>Snooze the reminders for <strong>{{.PR}}</strong> for {{.For}}? A new push or review ends the snooze early.</p>
+{{range $name, $values := .Query}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">{{end}}{{end}}
+<button type="submit">Snooze</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+// Handler serves the links made by Link. Opening a link shows a
+// confirmation form, so that mail scanners following it do not snooze
+// anything; submitting it records the snooze in snoozes, for at most
+// longest.
+func Handler(secret string, snoozes *Store, longest time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		now := time.Now()
+		key, d, err := verify(secret, r.Form, now)
+		if err != nil {
+			logger.Info("Rejected snooze link for %q: %v", r.Form.Get("pr"), err)
+			w.WriteHeader(http.StatusForbidden)
+			_ = page.Execute(w, map[string]any{"Error": err.Error()})
+			return
+		}
+		d = min(d, longest)
+		if r.Method == http.MethodGet {
+			_ = page.Execute(w, map[string]any{"PR": key, "For": d, "Query": r.Form})
+			return
+		}
+
+		until := now.Add(d)
+		if err := snoozes.Set(key, Snooze{Until: until, At: now, By: "link"}); err != nil {
+			logger.Error("Failed to save the snooze of %s: %v", key, err)
+			http.Error(w, "failed to save the snooze", http.StatusInternalServerError)
+			return
+		}
+		logger.Info("Snoozed reminders for %s until %s through a link", key, until.Format(time.RFC3339))
+		_ = page.Execute(w, map[string]any{"PR": key, "Until": until.Format("2006-01-02 15:04 MST")})
+	})
+}
@@ -0,0 +1,79 @@
+package snooze
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+)
+
+// stateKey is the state store key the snoozes are persisted under
+const stateKey = "snoozes"
+
+// Snooze silences the reminders of a pull request until Until, or until the
+// pull request gets a new push or review
+type Snooze struct {
+	Until   time.Time `json:"until"`
+	At      time.Time `json:"at"`                 // When it was requested; later activity ends it
+	By      string    `json:"by"`                 // Login of the commenter, or "link"
+	HeadSHA string    `json:"head_sha,omitempty"` // Head commit when first checked; a push ends it
+	Ended   string    `json:"ended,omitempty"`    // Why it ended before Until, if it did
+}
+
+// Active reports whether the snooze still silences reminders at now
+func (s Snooze) Active(now time.Time) bool {
+	return s.Ended == "" && now.Before(s.Until)
+}
+
+// Store keeps the snoozes of pull requests, by the key the watcher gives
+// them, in the state store. Snoozes stay until they expire, even when they
+// ended early, so that the command that set them is not applied twice.
+type Store struct {
+	mu      sync.Mutex
+	store   *state.Store
+	snoozes map[string]Snooze
+}
+
+// NewStore returns the snoozes kept in store, which may be nil to keep them
+// in memory only
+func NewStore(store *state.Store) (*Store, error) {
+	s := &Store{store: store, snoozes: make(map[string]Snooze)}
+	if store == nil {
+		return s, nil
+	}
+	if _, err := store.Get(stateKey, &s.snoozes); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the snooze of a pull request, if it has one
+func (s *Store) Get(key string) (Snooze, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snooze, ok := s.snoozes[key]
+	return snooze, ok
+}
+
+// Set records the snooze of a pull request, replacing any previous one, and
+// drops the snoozes that expired
+func (s *Store) Set(key string, snooze Snooze) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, existing := range s.snoozes {
+		if !now.Before(existing.Until) {
+			delete(s.snoozes, k)
+		}
+	}
+	s.snoozes[key] = snooze
+	return s.save()
+}
+
+// save persists the snoozes; the caller holds the lock
+func (s *Store) save() error {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.Put(stateKey, s.snoozes)
+}
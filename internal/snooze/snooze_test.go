@@ -0,0 +1,96 @@
+package snooze
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    Command
+		wantErr bool
+	}{
+		{"/pr-watcher snooze 2d", Command{Action: "snooze", Duration: 48 * time.Hour}, false},
+		{"/pr-watcher Snooze 1w", Command{Action: "snooze", Duration: 7 * 24 * time.Hour}, false},
+		{"/pr-watcher snooze 12h", Command{Action: "snooze", Duration: 12 * time.Hour}, false},
+		{"/pr-watcher snooze", Command{Action: "snooze", Duration: 24 * time.Hour}, false},
+		{"/pr-watcher ack", Command{Action: "snooze", Duration: 24 * time.Hour}, false},
+		{"/pr-watcher unsnooze", Command{Action: "unsnooze"}, false},
+		{"/pr-watcher snooze 0d", Command{}, true},
+		{"/pr-watcher snooze soon", Command{}, true},
+		{"/pr-watcher ping", Command{}, true},
+		{"/pr-watcher", Command{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCommand(tt.line, 24*time.Hour)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseCommand(%q) = %+v, %v; want %+v (error: %v)", tt.line, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	snoozes, err := NewStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Handler("s3cret", snoozes, 72*time.Hour)
+	link := Link("https://watcher.example.com/snooze", "s3cret", "acme/api#7", 168*time.Hour, time.Now().Add(time.Hour))
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		var body *strings.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(method, target, body)
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Opening the link only asks for confirmation
+	if rec := serve(http.MethodGet, "/snooze?"+u.RawQuery, nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<form") {
+		t.Fatalf("GET: status %d, body %q; want a confirmation form", rec.Code, rec.Body.String())
+	}
+	if _, ok := snoozes.Get("acme/api#7"); ok {
+		t.Fatal("GET snoozed the pull request")
+	}
+
+	tampered := u.Query()
+	tampered.Set("pr", "acme/api#8")
+	if rec := serve(http.MethodPost, "/snooze", tampered); rec.Code != http.StatusForbidden {
+		t.Errorf("tampered link: status %d, want 403", rec.Code)
+	}
+
+	if rec := serve(http.MethodPost, "/snooze", u.Query()); rec.Code != http.StatusOK {
+		t.Fatalf("POST: status %d, want 200", rec.Code)
+	}
+	s, ok := snoozes.Get("acme/api#7")
+	if !ok || !s.Active(time.Now()) || s.By != "link" {
+		t.Fatalf("snooze = %+v (found: %v), want an active snooze by link", s, ok)
+	}
+	// The requested 168h is capped at the longest snooze allowed
+	if s.Until.After(time.Now().Add(72 * time.Hour)) {
+		t.Errorf("snoozed until %v, want at most 72h from now", s.Until)
+	}
+
+	expired := Link("https://watcher.example.com/snooze", "s3cret", "acme/api#7", time.Hour, time.Now().Add(-time.Minute))
+	u, _ = url.Parse(expired)
+	if rec := serve(http.MethodPost, "/snooze", u.Query()); rec.Code != http.StatusForbidden {
+		t.Errorf("expired link: status %d, want 403", rec.Code)
+	}
+}
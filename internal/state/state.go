@@ -0,0 +1,100 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps small pieces of watcher state, such as cached pull requests,
+// across runs and restarts. Each key holds one JSON document. The whole
+// store is written to a single file; without a file it lives in memory only.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data map[string]json.RawMessage
+}
+
+// Open loads the store from path, which need not exist yet. An empty path
+// gives an in-memory store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, data: make(map[string]json.RawMessage)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.data); err != nil {
+			return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+// Get decodes the document stored under key into v and reports whether the
+// key was present
+func (s *Store) Get(key string, v any) (bool, error) {
+	s.mu.Lock()
+	raw, ok := s.data[key]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("failed to decode state %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// Put stores v under key and writes the store to its file
+func (s *Store) Put(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode state %q: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = raw
+	return s.save()
+}
+
+// save writes the store atomically by renaming a temporary file over it, so
+// a crash never leaves a truncated state file behind
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+)
+
+// forgeFor returns the forge a pull request was fetched from
+func (w *PRWatcher) forgeFor(pr *github.PullRequest) forge.Forge {
+	if pr.Source == "" {
+		return w.githubClient
+	}
+	return w.sources[pr.Source]
+}
+
+// commentEvent is the actions.comments.events name of a notification type
+func commentEvent(typ notifier.NotificationType) string {
+	switch typ {
+	case notifier.ApprovalReminder:
+		return "approval_reminder"
+	case notifier.MergeReminder:
+		return "merge_reminder"
+	case notifier.Escalation:
+		return "escalation"
+	case notifier.WaitingOnReviewer:
+		return "waiting_on_reviewer"
+	case notifier.WaitingOnAuthor:
+		return "waiting_on_author"
+	case notifier.CIFailing:
+		return "ci_failing"
+	default:
+		return ""
+	}
+}
+
+// comment keeps the reminder comment on pr current for a notification of
+// type typ, when comments are enabled for it and the PR's forge supports
+// them. Failures are added to result like failed emails.
+func (w *PRWatcher) comment(ctx context.Context, log *logger.Logger, result *NotificationResult, typ notifier.NotificationType, pr *github.PullRequest, threshold time.Duration) {
+	if !w.config.Actions.Comments.Commented(commentEvent(typ)) {
+		return
+	}
+	commenter, ok := w.forgeFor(pr).(forge.Commenter)
+	if !ok {
+		log.Debug("Reminder comments are not supported for PR #%d", pr.Number)
+		return
+	}
+
+	body := notifier.ReminderComment(typ, pr, threshold, w.commentMentions(typ, pr))
+	if w.config.Debug.SkipEmails {
+		log.Info("[SKIPPED] Would update the reminder comment on PR #%d", pr.Number)
+		return
+	}
+	if err := commenter.UpsertComment(ctx, pr.Owner, pr.Repo, pr.Number, notifier.CommentMarker, body); err != nil {
+		log.Error("Failed to update the reminder comment on PR #%d: %v", pr.Number, err)
+		result.Errors = append(result.Errors, fmt.Errorf("%s comment for PR #%d: %w", typ, pr.Number, err))
+		return
+	}
+	result.Comments++
+	log.Info("Updated the reminder comment on PR #%d", pr.Number)
+}
+
+// commentMentions returns who needs to act on a notification: the requested
+// reviewers (or actions.comments.reviewers) for approval and review, the
+// author for merging, addressing reviews and fixing checks, and everyone
+// involved plus the escalation contacts when escalating
+func (w *PRWatcher) commentMentions(typ notifier.NotificationType, pr *github.PullRequest) []string {
+	cfg := w.config.Actions.Comments
+	reviewers := pr.RequestedReviewers
+	if len(reviewers) == 0 {
+		reviewers = cfg.Reviewers
+	}
+
+	var author []string
+	if pr.User != nil && pr.User.Login != "" {
+		author = []string{pr.User.Login}
+	}
+
+	switch typ {
+	case notifier.ApprovalReminder, notifier.WaitingOnReviewer:
+		return reviewers
+	case notifier.MergeReminder, notifier.WaitingOnAuthor, notifier.CIFailing:
+		return author
+	case notifier.Escalation:
+		return append(append(append([]string{}, author...), reviewers...), cfg.EscalationMentions...)
+	default:
+		return nil
+	}
+}
+
+// sizeCategories are the values of PullRequest.SizeCategory
+var sizeCategories = []string{"XS", "S", "M", "L", "XL"}
+
+// slaLabel returns the SLA status label that applies to pr, following the
+// same rules as the notifications sent by processPR, or nil if none does
+func slaLabel(cfg config.LabelsConfig, pr *github.PullRequest, age time.Duration, thresholds config.PRTimeRules) *config.LabelConfig {
+	switch {
+	case pr.Draft:
+		if age >= thresholds.DraftTime {
+			return &cfg.Overdue
+		}
+	case age >= thresholds.MergeTime:
+		return &cfg.Escalated
+	case pr.ReviewCount < 2 && age >= thresholds.ApprovalTime:
+		return &cfg.NeedsReview
+	case pr.ReviewCount >= 2 && age >= thresholds.MergeReminderTime:
+		return &cfg.Overdue
+	}
+	return nil
+}
+
+// managedLabel reports whether name is one of the SLA or size labels the
+// watcher maintains. Like GitHub, it ignores case.
+func managedLabel(cfg config.LabelsConfig, name string) bool {
+	for _, label := range []string{cfg.NeedsReview.Name, cfg.Overdue.Name, cfg.Escalated.Name} {
+		if strings.EqualFold(name, label) {
+			return true
+		}
+	}
+	for _, size := range sizeCategories {
+		if strings.EqualFold(name, cfg.Size.Name+size) {
+			return true
+		}
+	}
+	return false
+}
+
+// label brings the SLA and size labels of pr in line with its status when
+// labels are enabled and the PR's forge supports them. Missing labels are
+// created with their configured colors and labels that no longer apply are
+// removed. Failures are added to result like failed emails.
+func (w *PRWatcher) label(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest, age time.Duration, thresholds config.PRTimeRules) {
+	cfg := w.config.Actions.Labels
+	if !cfg.Enabled {
+		return
+	}
+	labeler, ok := w.forgeFor(pr).(forge.Labeler)
+	if !ok {
+		log.Debug("Labels are not supported for PR #%d", pr.Number)
+		return
+	}
+
+	var want []config.LabelConfig
+	if label := slaLabel(cfg, pr, age, thresholds); label != nil {
+		want = append(want, *label)
+	}
+	if pr.SizeCategory != "" {
+		want = append(want, config.LabelConfig{Name: cfg.Size.Name + pr.SizeCategory, Color: cfg.Size.Color})
+	}
+
+	var add, remove []string
+	colors := make(map[string]string)
+	for _, label := range want {
+		if !slices.ContainsFunc(pr.Labels, func(l string) bool { return strings.EqualFold(l, label.Name) }) {
+			add = append(add, label.Name)
+			colors[label.Name] = label.Color
+		}
+	}
+	for _, l := range pr.Labels {
+		if managedLabel(cfg, l) && !slices.ContainsFunc(want, func(label config.LabelConfig) bool { return strings.EqualFold(l, label.Name) }) {
+			remove = append(remove, l)
+		}
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return
+	}
+
+	if w.config.Debug.SkipEmails {
+		log.Info("[SKIPPED] Would add labels %v to and remove labels %v from PR #%d", add, remove, pr.Number)
+		return
+	}
+	if err := updateLabels(ctx, labeler, pr, add, remove, colors); err != nil {
+		log.Error("Failed to update labels on PR #%d: %v", pr.Number, err)
+		result.Errors = append(result.Errors, fmt.Errorf("labels for PR #%d: %w", pr.Number, err))
+		return
+	}
+	result.Labeled++
+	log.Info("Updated labels on PR #%d: added %v, removed %v", pr.Number, add, remove)
+}
+
+func updateLabels(ctx context.Context, labeler forge.Labeler, pr *github.PullRequest, add, remove []string, colors map[string]string) error {
+	for _, name := range add {
+		if err := labeler.CreateLabel(ctx, pr.Owner, pr.Repo, name, colors[name]); err != nil {
+			return fmt.Errorf("failed to create label %s: %w", name, err)
+		}
+	}
+	if len(add) > 0 {
+		if err := labeler.AddLabels(ctx, pr.Owner, pr.Repo, pr.Number, add); err != nil {
+			return fmt.Errorf("failed to add labels: %w", err)
+		}
+	}
+	for _, name := range remove {
+		if err := labeler.RemoveLabel(ctx, pr.Owner, pr.Repo, pr.Number, name); err != nil {
+			return fmt.Errorf("failed to remove label %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+)
+
+// fakeForge records the changes the watcher makes to pull requests
+type fakeForge struct {
+	forge.Forge
+
+	created   map[string]string // Label name to color
+	added     []string
+	removed   []string
+	requested [][]string // Reviewers of each review request
+	comments  []string
+	closed    []int
+}
+
+func (f *fakeForge) UpsertComment(ctx context.Context, owner, repo string, number int, marker, body string) error {
+	f.comments = append(f.comments, body)
+	return nil
+}
+
+func (f *fakeForge) ClosePullRequest(ctx context.Context, owner, repo string, number int) error {
+	f.closed = append(f.closed, number)
+	return nil
+}
+
+func (f *fakeForge) CreateLabel(ctx context.Context, owner, repo, name, color string) error {
+	if f.created == nil {
+		f.created = make(map[string]string)
+	}
+	f.created[name] = color
+	return nil
+}
+
+func (f *fakeForge) AddLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	f.added = append(f.added, labels...)
+	return nil
+}
+
+func (f *fakeForge) RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error {
+	f.removed = append(f.removed, label)
+	return nil
+}
+
+func (f *fakeForge) RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers []string) error {
+	f.requested = append(f.requested, reviewers)
+	return nil
+}
+
+func (f *fakeForge) CodeOwners(ctx context.Context, pr *forge.PullRequest) ([]string, error) {
+	return nil, nil
+}
+
+func TestLabel_ReplacesStaleLabels(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Actions.Labels = config.LabelsConfig{
+		Enabled:     true,
+		NeedsReview: config.LabelConfig{Name: "sla:needs-review", Color: "fbca04"},
+		Overdue:     config.LabelConfig{Name: "sla:overdue", Color: "d93f0b"},
+		Escalated:   config.LabelConfig{Name: "sla:escalated", Color: "b60205"},
+		Size:        config.LabelConfig{Name: "size:", Color: "ededed"},
+	}
+	f := &fakeForge{}
+	w := NewPRWatcher(f, nil, nil, cfg)
+
+	pr := &github.PullRequest{
+		Number:       7,
+		Owner:        "acme",
+		Repo:         "api",
+		SizeCategory: "M",
+		Labels:       []string{"bug", "SLA:Needs-Review", "size:S", "size:M"},
+	}
+	thresholds := config.PRTimeRules{ApprovalTime: time.Hour, MergeReminderTime: 2 * time.Hour, MergeTime: 3 * time.Hour}
+	result := &NotificationResult{}
+	w.label(context.Background(), logger.Get(), result, pr, 4*time.Hour, thresholds)
+
+	if len(result.Errors) > 0 || result.Labeled != 1 {
+		t.Fatalf("result = %+v, want one labeled PR", result)
+	}
+	if !reflect.DeepEqual(f.added, []string{"sla:escalated"}) || f.created["sla:escalated"] != "b60205" {
+		t.Errorf("added %v (created %v), want sla:escalated in b60205", f.added, f.created)
+	}
+	if !reflect.DeepEqual(f.removed, []string{"SLA:Needs-Review", "size:S"}) {
+		t.Errorf("removed %v, want the needs-review and size:S labels", f.removed)
+	}
+}
+
+func TestComment_NotEditedAsThePRAges(t *testing.T) {
+	var (
+		body  string
+		edits int
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"login": "watcher"}`))
+	})
+	mux.HandleFunc("/api/v3/repos/acme/api/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var c struct{ Body string }
+			json.NewDecoder(r.Body).Decode(&c)
+			body = c.Body
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 1}`))
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]any{{"id": 1, "body": body, "user": map[string]string{"login": "watcher"}}})
+	})
+	mux.HandleFunc("/api/v3/repos/acme/api/issues/comments/1", func(w http.ResponseWriter, r *http.Request) {
+		edits++
+		w.Write([]byte(`{"id": 1}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := github.NewClientForConfig(config.GitHubConfig{BaseURL: server.URL + "/api/v3/", Token: "t", API: "rest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Actions.Comments = config.CommentsConfig{Enabled: true, Events: config.CommentEvents}
+	w := NewPRWatcher(client, nil, nil, cfg)
+
+	pr := &github.PullRequest{Owner: "acme", Repo: "api", Number: 7, User: &github.User{Login: "alice"}, RequestedReviewers: []string{"bob"}}
+	for _, age := range []time.Duration{25 * time.Hour, 49 * time.Hour} {
+		pr.CreatedAt = time.Now().Add(-age)
+		result := &NotificationResult{}
+		w.comment(context.Background(), logger.Get(), result, notifier.ApprovalReminder, pr, 24*time.Hour)
+		if len(result.Errors) > 0 {
+			t.Fatalf("comment failed: %v", result.Errors)
+		}
+	}
+	if body == "" || edits != 0 {
+		t.Errorf("Expected the comment to be created once and not edited as the PR aged, got %d edits of %q", edits, body)
+	}
+}
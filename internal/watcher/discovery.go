@@ -0,0 +1,331 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+)
+
+// repoCache remembers discovered repositories between runs so each owner's
+// repositories are only re-listed every discovery.refresh_interval
+type repoCache struct {
+	mu           sync.Mutex
+	owners       map[string]*discoveredRepos // keyed by source name and lower-cased owner
+	installation installationRepos
+}
+
+// installationRepos are the repositories of the GitHub App installations,
+// grouped by owner
+type installationRepos struct {
+	owners    []string
+	repos     map[string][]string
+	refreshed time.Time
+}
+
+type discoveredRepos struct {
+	repos     []string
+	refreshed time.Time
+}
+
+// reset forces the next run to re-list repositories
+func (c *repoCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.owners = nil
+	c.installation = installationRepos{}
+}
+
+// installationLister is implemented by forges that authenticate as an app
+// installed on several accounts
+type installationLister interface {
+	InstallationRepositories(ctx context.Context) ([]*forge.Repository, error)
+}
+
+// source is a forge together with the owners to check on it
+type source struct {
+	name    string // Empty for GitHub
+	forge   forge.Forge
+	targets []config.OwnerTarget
+}
+
+// describe names an owner of the source for log messages
+func (s source) describe(owner string) string {
+	if s.name == "" {
+		return owner
+	}
+	return s.name + " " + owner
+}
+
+// sourcesToCheck returns GitHub, when configured, followed by the configured
+// sources whose forge could be created. A failed GitHub App discovery is
+// returned along with them.
+func (w *PRWatcher) sourcesToCheck(ctx context.Context, log *logger.Logger) ([]source, error) {
+	var (
+		sources []source
+		err     error
+	)
+	if w.githubClient != nil {
+		var targets []config.OwnerTarget
+		targets, err = w.targets(ctx, log)
+		sources = append(sources, source{forge: w.githubClient, targets: targets})
+	}
+	for _, cfg := range w.config.Sources {
+		f, ok := w.sources[cfg.Name]
+		if !ok {
+			continue
+		}
+		sources = append(sources, source{name: cfg.Name, forge: f, targets: cfg.Targets()})
+	}
+	return sources, err
+}
+
+// fetchResult holds the pull requests fetched for a run together with the
+// repositories that could not be fetched
+type fetchResult struct {
+	prs    []*github.PullRequest
+	repos  int     // Repositories checked
+	failed int     // Repositories that could not be fetched
+	errors []error // One per failed repository, plus failed discoveries
+}
+
+// allFailed reports whether there were errors and no repository could be
+// fetched
+func (r *fetchResult) allFailed() bool {
+	return len(r.errors) > 0 && r.failed == r.repos
+}
+
+// fetchPullRequests fetches the open pull requests of every configured
+// owner, on GitHub and on the other sources. A repository that fails is
+// recorded in the result and does not prevent the others from being
+// processed. With the webhook cache, cached repositories are read from it
+// and only the others are fetched, unless a full reconciliation is due.
+func (w *PRWatcher) fetchPullRequests(ctx context.Context, log *logger.Logger) *fetchResult {
+	result := &fetchResult{}
+	started := time.Now()
+	reconcile := w.cache != nil && w.cache.ReconcileDue(w.config.Webhook.ReconcileInterval)
+	if reconcile {
+		log.Info("Reconciling the pull request cache with GitHub")
+	}
+
+	sources, err := w.sourcesToCheck(ctx, log)
+	if err != nil {
+		result.errors = append(result.errors, err)
+	}
+	for _, src := range sources {
+		// The webhook cache only holds GitHub pull requests
+		cache := w.cache
+		if src.name != "" {
+			cache = nil
+		}
+
+		for _, target := range src.targets {
+			if ctx.Err() != nil {
+				break
+			}
+			repos, err := w.resolveRepos(ctx, log, src, target)
+			if err != nil {
+				result.errors = append(result.errors, err)
+			}
+			result.repos += len(repos)
+
+			if cache != nil && !reconcile {
+				var cached []*github.PullRequest
+				cached, repos = cache.PullRequests(target.Owner, repos)
+				result.prs = append(result.prs, cached...)
+				if len(repos) == 0 {
+					log.Debug("Using cached PRs for all %s repositories", target.Owner)
+					continue
+				}
+			}
+			log.Info("Checking PRs for %s repositories: %v", src.describe(target.Owner), repos)
+
+			prs, err := src.forge.GetPullRequests(ctx, target.Owner, repos)
+			for _, pr := range prs {
+				pr.Source = src.name
+			}
+			result.prs = append(result.prs, prs...)
+
+			fetched := slices.Clone(repos)
+			var failed forge.FetchErrors
+			switch {
+			case errors.As(err, &failed):
+				for _, repoErr := range failed {
+					log.With(logger.FieldRepo, repoErr.Owner+"/"+repoErr.Repo).Error("Failed to fetch pull requests: %v", repoErr.Err)
+					result.errors = append(result.errors, repoErr)
+					result.failed++
+					fetched = slices.DeleteFunc(fetched, func(repo string) bool { return repo == repoErr.Repo })
+				}
+			case err != nil:
+				log.Error("Failed to fetch pull requests of %s: %v", src.describe(target.Owner), err)
+				for _, repo := range repos {
+					result.errors = append(result.errors, &forge.RepoError{Owner: target.Owner, Repo: repo, Err: err})
+				}
+				result.failed += len(repos)
+				continue
+			}
+
+			if cache != nil {
+				if err := cache.Replace(target.Owner, fetched, prs); err != nil {
+					log.Error("%v", err)
+				}
+			}
+		}
+	}
+
+	if reconcile && ctx.Err() == nil {
+		if err := w.cache.MarkReconciled(started); err != nil {
+			log.Error("%v", err)
+		}
+	}
+	return result
+}
+
+// targets returns the configured owners and, with github.app.discover_repos,
+// the repositories of every installation of the GitHub App that pass the
+// discovery filters. If listing them fails, the previous list is used and
+// the error returned.
+func (w *PRWatcher) targets(ctx context.Context, log *logger.Logger) ([]config.OwnerTarget, error) {
+	targets := w.config.GitHub.Targets()
+	if !w.config.GitHub.App.DiscoverRepos {
+		return targets, nil
+	}
+
+	installed, err := w.installationRepos(ctx, log)
+	for _, owner := range installed.owners {
+		i := slices.IndexFunc(targets, func(t config.OwnerTarget) bool {
+			return strings.EqualFold(t.Owner, owner)
+		})
+		if i < 0 {
+			targets = append(targets, config.OwnerTarget{Owner: owner})
+			i = len(targets) - 1
+		}
+		for _, repo := range installed.repos[owner] {
+			if !slices.Contains(targets[i].Repos, repo) {
+				targets[i].Repos = append(targets[i].Repos, repo)
+			}
+		}
+	}
+	return targets, err
+}
+
+func (w *PRWatcher) installationRepos(ctx context.Context, log *logger.Logger) (installationRepos, error) {
+	w.repos.mu.Lock()
+	defer w.repos.mu.Unlock()
+
+	cached := w.repos.installation
+	if !cached.refreshed.IsZero() && time.Since(cached.refreshed) < w.config.GitHub.Discovery.RefreshInterval {
+		return cached, nil
+	}
+
+	filter, err := github.NewRepoFilter(w.config.GitHub.Discovery)
+	if err != nil {
+		log.Error("Repository discovery is misconfigured: %v", err)
+		return cached, fmt.Errorf("repository discovery is misconfigured: %w", err)
+	}
+
+	lister, ok := w.githubClient.(installationLister)
+	if !ok {
+		return cached, nil
+	}
+	all, err := lister.InstallationRepositories(ctx)
+	if err != nil {
+		log.Error("Listing GitHub App installation repositories failed, using the previous list: %v", err)
+		return cached, fmt.Errorf("failed to list GitHub App installation repositories: %w", err)
+	}
+
+	result := installationRepos{repos: make(map[string][]string), refreshed: time.Now()}
+	for _, repo := range all {
+		if !filter.Match(repo) {
+			continue
+		}
+		if _, ok := result.repos[repo.Owner]; !ok {
+			result.owners = append(result.owners, repo.Owner)
+		}
+		result.repos[repo.Owner] = append(result.repos[repo.Owner], repo.Name)
+	}
+	log.Debug("GitHub App installations give access to %d repositories of %d owners", len(all), len(result.owners))
+
+	w.repos.installation = result
+	return result, nil
+}
+
+// resolveRepos returns the repositories to check for an owner: those listed
+// in the configuration followed by any discovered ones not already listed.
+// If a refresh fails the previously discovered list is used and the error
+// returned.
+func (w *PRWatcher) resolveRepos(ctx context.Context, log *logger.Logger, src source, target config.OwnerTarget) ([]string, error) {
+	if !target.Discovery.Enabled {
+		return target.Repos, nil
+	}
+
+	discovered, err := w.discoveredRepos(ctx, log, src, target)
+
+	seen := make(map[string]bool, len(target.Repos)+len(discovered))
+	repos := make([]string, 0, len(target.Repos)+len(discovered))
+	for _, repo := range append(append([]string{}, target.Repos...), discovered...) {
+		if !seen[repo] {
+			seen[repo] = true
+			repos = append(repos, repo)
+		}
+	}
+	return repos, err
+}
+
+func (w *PRWatcher) discoveredRepos(ctx context.Context, log *logger.Logger, src source, target config.OwnerTarget) ([]string, error) {
+	w.repos.mu.Lock()
+	defer w.repos.mu.Unlock()
+
+	if w.repos.owners == nil {
+		w.repos.owners = make(map[string]*discoveredRepos)
+	}
+	key := src.name + "/" + strings.ToLower(target.Owner)
+	cached, ok := w.repos.owners[key]
+	if !ok {
+		cached = &discoveredRepos{}
+		w.repos.owners[key] = cached
+	}
+
+	discovery := target.Discovery
+	if !cached.refreshed.IsZero() && time.Since(cached.refreshed) < discovery.RefreshInterval {
+		return cached.repos, nil
+	}
+
+	filter, err := github.NewRepoFilter(discovery)
+	if err != nil {
+		log.Error("Repository discovery for %s is misconfigured: %v", src.describe(target.Owner), err)
+		return cached.repos, fmt.Errorf("repository discovery for %s is misconfigured: %w", src.describe(target.Owner), err)
+	}
+
+	all, err := src.forge.ListRepositories(ctx, target.Owner)
+	if err != nil {
+		log.Error("Repository discovery for %s failed, using previously discovered repositories: %v", src.describe(target.Owner), err)
+		return cached.repos, fmt.Errorf("repository discovery for %s failed: %w", src.describe(target.Owner), err)
+	}
+
+	repos := filter.Filter(all)
+	if !cached.refreshed.IsZero() {
+		known := make(map[string]bool, len(cached.repos))
+		for _, repo := range cached.repos {
+			known[repo] = true
+		}
+		for _, repo := range repos {
+			if !known[repo] {
+				log.Info("Discovered new repository %s/%s", target.Owner, repo)
+			}
+		}
+	}
+	log.Debug("Discovered %d of %d repositories of %s", len(repos), len(all), src.describe(target.Owner))
+
+	cached.repos = repos
+	cached.refreshed = time.Now()
+	return repos, nil
+}
@@ -0,0 +1,287 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+)
+
+// stateKeyDrafts is the state store key of the draft lifecycle
+const stateKeyDrafts = "drafts.lifecycle"
+
+// draftState is what the draft lifecycle remembers about a draft
+type draftState struct {
+	HeadSHA string    `json:"head_sha"`
+	Pushed  time.Time `json:"pushed,omitempty"` // When a new HeadSHA was first seen
+	Warned  time.Time `json:"warned,omitempty"` // When the stale warning was left
+}
+
+// draftTracker keeps the lifecycle state of drafts across runs. Pushes are
+// noticed by a change of the head commit, so they are seen even when the
+// pull requests come without a timeline.
+type draftTracker struct {
+	mu     sync.Mutex
+	store  *state.Store // nil keeps the state for this run only
+	drafts map[string]draftState
+}
+
+// newDraftTracker loads the draft lifecycle state. When prs are all the open
+// pull requests, complete drops the state of those that were closed or are
+// no longer drafts.
+func newDraftTracker(store *state.Store, prs []*github.PullRequest, complete bool) (*draftTracker, error) {
+	t := &draftTracker{store: store, drafts: make(map[string]draftState)}
+	if store != nil {
+		if _, err := store.Get(stateKeyDrafts, &t.drafts); err != nil {
+			return nil, err
+		}
+	}
+	if complete {
+		open := make(map[string]bool)
+		for _, pr := range prs {
+			if pr.Draft {
+				open[prKey(pr)] = true
+			}
+		}
+		for key := range t.drafts {
+			if !open[key] {
+				delete(t.drafts, key)
+			}
+		}
+	}
+	return t, nil
+}
+
+// observe returns the state of a draft, updated for its current head commit
+func (t *draftTracker) observe(pr *github.PullRequest) (draftState, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := prKey(pr)
+	st, ok := t.drafts[key]
+	var sha string
+	if pr.Head != nil {
+		sha = pr.Head.SHA
+	}
+	if ok && st.HeadSHA == sha {
+		return st, nil
+	}
+	if ok {
+		st.Pushed = time.Now()
+	}
+	st.HeadSHA = sha
+	t.drafts[key] = st
+	return st, t.save()
+}
+
+// update records the state of a draft
+func (t *draftTracker) update(pr *github.PullRequest, st draftState) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drafts[prKey(pr)] = st
+	return t.save()
+}
+
+// forget drops the state of a pull request
+func (t *draftTracker) forget(pr *github.PullRequest) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := prKey(pr)
+	if _, ok := t.drafts[key]; !ok {
+		return nil
+	}
+	delete(t.drafts, key)
+	return t.save()
+}
+
+func (t *draftTracker) save() error {
+	if t.store == nil {
+		return nil
+	}
+	return t.store.Put(stateKeyDrafts, t.drafts)
+}
+
+// newDraftTracker prepares the draft lifecycle for a run, if enabled
+func (w *PRWatcher) newDraftTracker(log *logger.Logger, prs []*github.PullRequest, complete bool) *draftTracker {
+	if !w.config.Actions.Drafts.Enabled {
+		return nil
+	}
+	t, err := newDraftTracker(w.store, prs, complete)
+	if err != nil {
+		log.Error("Failed to load the draft lifecycle state, skipping stale drafts: %v", err)
+		return nil
+	}
+	return t
+}
+
+// lastActivity returns when a draft was last pushed to or commented on
+func lastActivity(pr *github.PullRequest, st draftState) time.Time {
+	last := pr.CreatedAt
+	if st.Pushed.After(last) {
+		last = st.Pushed
+	}
+	for _, event := range pr.Timeline {
+		switch event.Type {
+		case "commit", "force_push", "comment":
+			if event.CreatedAt.After(last) {
+				last = event.CreatedAt
+			}
+		}
+	}
+	return last
+}
+
+// draftLifecycle warns about drafts that have had no push or comment for
+// actions.drafts.warn_after, with a comment and a label, and with close set
+// closes them once they reach close_after. New activity withdraws the
+// warning. Drafts with the exempt label are left alone, and so are drafts
+// without a timeline, fetched over REST, whose comments are unknown. It
+// reports whether pr was closed.
+func (w *PRWatcher) draftLifecycle(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest) bool {
+	cfg := w.config.Actions.Drafts
+	if w.drafts == nil {
+		return false
+	}
+	hasLabel := func(name string) bool {
+		return slices.ContainsFunc(pr.Labels, func(l string) bool { return strings.EqualFold(l, name) })
+	}
+
+	if !pr.Draft || hasLabel(cfg.ExemptLabel) {
+		if hasLabel(cfg.Label.Name) {
+			w.withdrawDraftWarning(ctx, log, result, pr)
+		}
+		if err := w.drafts.forget(pr); err != nil {
+			log.Error("Failed to save the draft lifecycle state: %v", err)
+		}
+		return false
+	}
+
+	st, err := w.drafts.observe(pr)
+	if err != nil {
+		log.Error("Failed to save the draft lifecycle state: %v", err)
+	}
+	last := lastActivity(pr, st)
+	idle := time.Since(last)
+
+	switch {
+	case !st.Warned.IsZero() && last.After(st.Warned):
+		log.Info("Draft PR #%d has new activity, withdrawing the stale warning", pr.Number)
+		w.withdrawDraftWarning(ctx, log, result, pr)
+		st.Warned = time.Time{}
+		if err := w.drafts.update(pr, st); err != nil {
+			log.Error("Failed to save the draft lifecycle state: %v", err)
+		}
+	case len(pr.Timeline) == 0:
+		log.Debug("Draft PR #%d has no timeline, so it is not checked for staleness", pr.Number)
+	case st.Warned.IsZero() && idle >= cfg.WarnAfter:
+		if w.warnStaleDraft(ctx, log, result, pr, idle) {
+			st.Warned = time.Now()
+			if err := w.drafts.update(pr, st); err != nil {
+				log.Error("Failed to save the draft lifecycle state: %v", err)
+			}
+		}
+	case !st.Warned.IsZero() && cfg.Close && idle >= cfg.CloseAfter && time.Since(st.Warned) >= cfg.CloseAfter-cfg.WarnAfter:
+		if w.closeStaleDraft(ctx, log, result, pr, idle) {
+			if err := w.drafts.forget(pr); err != nil {
+				log.Error("Failed to save the draft lifecycle state: %v", err)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// warnStaleDraft comments on and labels a stale draft and reports whether
+// the warning was left
+func (w *PRWatcher) warnStaleDraft(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest, idle time.Duration) bool {
+	cfg := w.config.Actions.Drafts
+	f := w.forgeFor(pr)
+	commenter, canComment := f.(forge.Commenter)
+	labeler, canLabel := f.(forge.Labeler)
+	if !canComment && !canLabel {
+		log.Debug("Stale draft warnings are not supported for PR #%d", pr.Number)
+		return false
+	}
+
+	var closeAt time.Time
+	if cfg.Close {
+		closeAt = time.Now().Add(cfg.CloseAfter - idle)
+		if minClose := time.Now().Add(cfg.CloseAfter - cfg.WarnAfter); closeAt.Before(minClose) {
+			closeAt = minClose
+		}
+	}
+	if w.config.Debug.SkipEmails {
+		log.Info("[SKIPPED] Would warn about stale draft PR #%d (no activity for %v)", pr.Number, idle)
+		return false
+	}
+
+	if canComment {
+		body := notifier.StaleDraftComment(pr, idle, closeAt, cfg.ExemptLabel)
+		if err := commenter.UpsertComment(ctx, pr.Owner, pr.Repo, pr.Number, notifier.CommentMarker, body); err != nil {
+			log.Error("Failed to warn about stale draft PR #%d: %v", pr.Number, err)
+			result.Errors = append(result.Errors, fmt.Errorf("stale draft warning for PR #%d: %w", pr.Number, err))
+			return false
+		}
+	}
+	if canLabel {
+		err := updateLabels(ctx, labeler, pr, []string{cfg.Label.Name}, nil, map[string]string{cfg.Label.Name: cfg.Label.Color})
+		if err != nil {
+			log.Error("Failed to label stale draft PR #%d: %v", pr.Number, err)
+			result.Errors = append(result.Errors, fmt.Errorf("stale draft label for PR #%d: %w", pr.Number, err))
+		}
+	}
+	result.DraftsWarned++
+	log.Info("Warned about stale draft PR #%d (no activity for %v)", pr.Number, idle)
+	return true
+}
+
+// closeStaleDraft explains and closes a stale draft and reports whether it
+// was closed
+func (w *PRWatcher) closeStaleDraft(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest, idle time.Duration) bool {
+	closer, ok := w.forgeFor(pr).(forge.Closer)
+	if !ok {
+		log.Debug("Closing stale drafts is not supported for PR #%d", pr.Number)
+		return false
+	}
+	if w.config.Debug.SkipEmails {
+		log.Info("[SKIPPED] Would close stale draft PR #%d (no activity for %v)", pr.Number, idle)
+		return false
+	}
+
+	if commenter, ok := w.forgeFor(pr).(forge.Commenter); ok {
+		body := notifier.ClosedDraftComment(pr, idle, w.config.Actions.Drafts.ExemptLabel)
+		if err := commenter.UpsertComment(ctx, pr.Owner, pr.Repo, pr.Number, notifier.CommentMarker, body); err != nil {
+			log.Error("Failed to explain closing stale draft PR #%d, leaving it open: %v", pr.Number, err)
+			result.Errors = append(result.Errors, fmt.Errorf("closing stale draft PR #%d: %w", pr.Number, err))
+			return false
+		}
+	}
+	if err := closer.ClosePullRequest(ctx, pr.Owner, pr.Repo, pr.Number); err != nil {
+		log.Error("Failed to close stale draft PR #%d: %v", pr.Number, err)
+		result.Errors = append(result.Errors, fmt.Errorf("closing stale draft PR #%d: %w", pr.Number, err))
+		return false
+	}
+	result.DraftsClosed++
+	log.Info("Closed stale draft PR #%d (no activity for %v)", pr.Number, idle)
+	return true
+}
+
+// withdrawDraftWarning removes the stale draft label from a pull request
+func (w *PRWatcher) withdrawDraftWarning(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest) {
+	labeler, ok := w.forgeFor(pr).(forge.Labeler)
+	if !ok || w.config.Debug.SkipEmails {
+		return
+	}
+	if err := labeler.RemoveLabel(ctx, pr.Owner, pr.Repo, pr.Number, w.config.Actions.Drafts.Label.Name); err != nil {
+		log.Error("Failed to remove the stale draft label from PR #%d: %v", pr.Number, err)
+		result.Errors = append(result.Errors, fmt.Errorf("stale draft label for PR #%d: %w", pr.Number, err))
+	}
+}
@@ -0,0 +1,86 @@
+package watcher
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+)
+
+func TestDraftLifecycle(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Actions.Drafts = config.DraftsConfig{
+		Enabled:     true,
+		WarnAfter:   time.Hour,
+		CloseAfter:  2 * time.Hour,
+		Close:       true,
+		Label:       config.LabelConfig{Name: "stale-draft", Color: "cfd3d7"},
+		ExemptLabel: "keep-open",
+	}
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeForge{}
+	w := NewPRWatcher(f, nil, nil, cfg)
+	w.SetStore(store)
+
+	run := func(pr *github.PullRequest) (bool, *NotificationResult) {
+		run := w.snapshot()
+		run.drafts = run.newDraftTracker(logger.Get(), []*github.PullRequest{pr}, true)
+		result := &NotificationResult{}
+		return run.draftLifecycle(context.Background(), logger.Get(), result, pr), result
+	}
+	pr := &github.PullRequest{
+		Number:    5,
+		Owner:     "acme",
+		Repo:      "api",
+		Draft:     true,
+		CreatedAt: time.Now().Add(-3 * time.Hour),
+		Head:      &github.Branch{Ref: "wip", SHA: "abc"},
+	}
+
+	// Without a timeline its comments are unknown, so it is not warned about
+	if closed, result := run(pr); closed || result.DraftsWarned != 0 {
+		t.Fatalf("run without timeline: closed = %v, result = %+v, want nothing", closed, result)
+	}
+	pr.Timeline = []github.TimelineEvent{{Type: "commit", Actor: "alice", CreatedAt: pr.CreatedAt}}
+
+	// A draft idle past warn_after is warned about once
+	if closed, result := run(pr); closed || result.DraftsWarned != 1 {
+		t.Fatalf("first run: closed = %v, result = %+v, want one warning", closed, result)
+	}
+	if closed, result := run(pr); closed || result.DraftsWarned != 0 {
+		t.Fatalf("second run: closed = %v, result = %+v, want nothing new", closed, result)
+	}
+	if len(f.comments) != 1 || !reflect.DeepEqual(f.added, []string{"stale-draft"}) {
+		t.Fatalf("comments %d, added %v, want one comment and the stale-draft label", len(f.comments), f.added)
+	}
+
+	// A push withdraws the warning
+	pushed := *pr
+	pushed.Head = &github.Branch{Ref: "wip", SHA: "def"}
+	pushed.Labels = []string{"stale-draft"}
+	run(&pushed)
+	if !reflect.DeepEqual(f.removed, []string{"stale-draft"}) {
+		t.Fatalf("removed %v, want the stale-draft label", f.removed)
+	}
+
+	// Once warned long enough ago, the draft is closed
+	key := prKey(pr)
+	st := map[string]draftState{key: {HeadSHA: "abc", Warned: time.Now().Add(-90 * time.Minute)}}
+	if err := store.Put(stateKeyDrafts, st); err != nil {
+		t.Fatal(err)
+	}
+	if closed, result := run(pr); !closed || result.DraftsClosed != 1 {
+		t.Fatalf("closing run: closed = %v, result = %+v, want the draft closed", closed, result)
+	}
+	if !reflect.DeepEqual(f.closed, []int{5}) {
+		t.Errorf("closed %v, want [5]", f.closed)
+	}
+}
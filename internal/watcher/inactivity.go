@@ -0,0 +1,46 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+)
+
+// remindWaiting reminds whoever pr is waiting on, its reviewers or its
+// author, once it has waited on them for the rules.inactivity threshold
+func (w *PRWatcher) remindWaiting(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest) {
+	rules := w.config.Rules.Inactivity
+	who, since := pr.Waiting()
+	waited := time.Since(since)
+
+	typ, threshold := notifier.WaitingOnReviewer, rules.WaitingOnReviewer
+	if who == forge.WaitingOnAuthor {
+		typ, threshold = notifier.WaitingOnAuthor, rules.WaitingOnAuthor
+	}
+	if threshold == 0 || waited < threshold {
+		log.Debug("PR #%d is waiting on its %s for %v (threshold: %v)", pr.Number, who, waited, threshold)
+		return
+	}
+
+	log = log.With(logger.FieldNotificationType, typ.String())
+	log.Debug("PR #%d has waited on its %s too long (waited: %v, threshold: %v)", pr.Number, who, waited, threshold)
+	var err error
+	if typ == notifier.WaitingOnAuthor {
+		err = w.notifier.SendWaitingOnAuthor(ctx, pr, waited, threshold, w.snoozeLinks(pr)...)
+	} else {
+		err = w.notifier.SendWaitingOnReviewer(ctx, pr, waited, threshold, pr.Reviewers(), w.snoozeLinks(pr)...)
+	}
+	if err != nil {
+		log.Error("Failed to send %s reminder for PR #%d: %v", typ, pr.Number, err)
+		result.Errors = append(result.Errors, fmt.Errorf("%s reminder for PR #%d: %w", typ, pr.Number, err))
+	} else {
+		result.WaitingReminders++
+		log.Info("Sent %s reminder for PR #%d", typ, pr.Number)
+	}
+	w.comment(ctx, log, result, typ, pr, threshold)
+}
@@ -0,0 +1,234 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+)
+
+// stateKeyReviewers is the state store key of the reviewer assignments
+const stateKeyReviewers = "reviewers.assignments"
+
+// reviewerState is what reviewer assignment keeps across runs
+type reviewerState struct {
+	Next     int                             `json:"next"`     // Position in actions.reviewers.team for round_robin
+	Assigned map[string]map[string]time.Time `json:"assigned"` // PRs each reviewer was requested on by the watcher, by lower-cased login and PR key
+}
+
+// reviewerAssigner chooses reviewers for the pull requests of a run. A
+// reviewer's load is the number of open pull requests they are requested
+// on, counting requests made by the watcher that the fetched pull requests
+// may not show yet.
+type reviewerAssigner struct {
+	mu    sync.Mutex
+	store *state.Store // nil keeps assignments for this run only
+	state reviewerState
+	load  map[string]map[string]bool // PR keys by lower-cased login
+}
+
+// prKey identifies a pull request across sources
+func prKey(pr *github.PullRequest) string {
+	key := pr.FullName() + "#" + strconv.Itoa(pr.Number)
+	if pr.Source != "" {
+		key = pr.Source + ":" + key
+	}
+	return key
+}
+
+// newReviewerAssigner loads the assignments of previous runs and counts the
+// review requests on prs. When prs are all the open pull requests, complete
+// drops the assignments of pull requests that were closed or whose reviewer
+// is no longer requested.
+func newReviewerAssigner(store *state.Store, prs []*github.PullRequest, complete bool) (*reviewerAssigner, error) {
+	a := &reviewerAssigner{store: store, load: make(map[string]map[string]bool)}
+	if store != nil {
+		if _, err := store.Get(stateKeyReviewers, &a.state); err != nil {
+			return nil, err
+		}
+	}
+	if a.state.Assigned == nil {
+		a.state.Assigned = make(map[string]map[string]time.Time)
+	}
+
+	requested := make(map[string]map[string]bool)
+	for _, pr := range prs {
+		for _, reviewer := range pr.RequestedReviewers {
+			login := strings.ToLower(reviewer)
+			if requested[login] == nil {
+				requested[login] = make(map[string]bool)
+			}
+			requested[login][prKey(pr)] = true
+		}
+	}
+
+	for login, keys := range a.state.Assigned {
+		for key := range keys {
+			if complete && !requested[login][key] {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(a.state.Assigned, login)
+		}
+	}
+	for login, keys := range requested {
+		a.load[login] = keys
+	}
+	for login, keys := range a.state.Assigned {
+		if a.load[login] == nil {
+			a.load[login] = make(map[string]bool)
+		}
+		for key := range keys {
+			a.load[login][key] = true
+		}
+	}
+	return a, nil
+}
+
+// newReviewerAssigner prepares reviewer assignment for a run, if enabled.
+// Without it no reviewers are requested.
+func (w *PRWatcher) newReviewerAssigner(log *logger.Logger, prs []*github.PullRequest, complete bool) *reviewerAssigner {
+	if !w.config.Actions.Reviewers.Enabled {
+		return nil
+	}
+	a, err := newReviewerAssigner(w.store, prs, complete)
+	if err != nil {
+		log.Error("Failed to load reviewer assignments, not requesting reviewers: %v", err)
+		return nil
+	}
+	return a
+}
+
+// choose picks up to count reviewers for pr from candidates, never its
+// author or someone who already reviewed it. With roundRobin they are taken
+// in turn, continuing where the previous pick stopped; otherwise the least
+// loaded come first, in candidate order on ties.
+func (a *reviewerAssigner) choose(pr *github.PullRequest, candidates []string, count int, roundRobin bool) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	eligible := func(login string) bool {
+		if pr.User != nil && strings.EqualFold(login, pr.User.Login) {
+			return false
+		}
+		return !slices.ContainsFunc(pr.Reviews, func(r github.Review) bool { return strings.EqualFold(r.User, login) })
+	}
+
+	var chosen []string
+	if roundRobin {
+		next := a.state.Next
+		for i := 0; i < len(candidates) && len(chosen) < count; i++ {
+			pos := (a.state.Next + i) % len(candidates)
+			if eligible(candidates[pos]) {
+				chosen = append(chosen, candidates[pos])
+				next = pos + 1
+			}
+		}
+		if len(candidates) > 0 {
+			a.state.Next = next % len(candidates)
+		}
+		return chosen
+	}
+
+	ordered := slices.Clone(candidates)
+	slices.SortStableFunc(ordered, func(x, y string) int {
+		return len(a.load[strings.ToLower(x)]) - len(a.load[strings.ToLower(y)])
+	})
+	for _, login := range ordered {
+		if len(chosen) == count {
+			break
+		}
+		if eligible(login) {
+			chosen = append(chosen, login)
+		}
+	}
+	return chosen
+}
+
+// record counts the requested reviewers towards their load and persists the
+// assignments
+func (a *reviewerAssigner) record(pr *github.PullRequest, reviewers []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := prKey(pr)
+	for _, reviewer := range reviewers {
+		login := strings.ToLower(reviewer)
+		if a.state.Assigned[login] == nil {
+			a.state.Assigned[login] = make(map[string]time.Time)
+		}
+		a.state.Assigned[login][key] = time.Now()
+		if a.load[login] == nil {
+			a.load[login] = make(map[string]bool)
+		}
+		a.load[login][key] = true
+	}
+	if a.store == nil {
+		return nil
+	}
+	return a.store.Put(stateKeyReviewers, a.state)
+}
+
+// requestReviewers requests reviewers for a pull request that passed
+// approval_time without enough reviews and without any requested reviewer,
+// in the repositories opted in through actions.reviewers. It returns pr
+// with the new reviewers, or pr itself if none were requested. Failures
+// are added to result like failed emails.
+func (w *PRWatcher) requestReviewers(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest, age time.Duration, thresholds config.PRTimeRules) *github.PullRequest {
+	cfg := w.config.Actions.Reviewers
+	if pr.Draft || len(pr.RequestedReviewers) > 0 || pr.ReviewCount >= 2 || age < thresholds.ApprovalTime ||
+		w.assigner == nil || !cfg.Applies(w.config.GitHub.Owner, pr.FullName()) {
+		return pr
+	}
+	requester, ok := w.forgeFor(pr).(forge.ReviewRequester)
+	if !ok {
+		log.Debug("Requesting reviewers is not supported for PR #%d", pr.Number)
+		return pr
+	}
+
+	candidates := cfg.Team
+	if cfg.Strategy == "codeowners" {
+		owners, err := requester.CodeOwners(ctx, pr)
+		if err != nil {
+			log.Error("Failed to get the code owners of PR #%d: %v", pr.Number, err)
+			result.Errors = append(result.Errors, fmt.Errorf("code owners for PR #%d: %w", pr.Number, err))
+			return pr
+		}
+		candidates = owners
+	}
+	reviewers := w.assigner.choose(pr, candidates, cfg.Count, cfg.Strategy == "round_robin")
+	if len(reviewers) == 0 {
+		log.Info("No reviewer to request for PR #%d (strategy: %s)", pr.Number, cfg.Strategy)
+		return pr
+	}
+
+	if w.config.Debug.SkipEmails {
+		log.Info("[SKIPPED] Would request reviews from %v on PR #%d", reviewers, pr.Number)
+		return pr
+	}
+	if err := requester.RequestReviewers(ctx, pr.Owner, pr.Repo, pr.Number, reviewers); err != nil {
+		log.Error("Failed to request reviewers for PR #%d: %v", pr.Number, err)
+		result.Errors = append(result.Errors, fmt.Errorf("reviewers for PR #%d: %w", pr.Number, err))
+		return pr
+	}
+	if err := w.assigner.record(pr, reviewers); err != nil {
+		log.Error("Failed to save reviewer assignments: %v", err)
+	}
+	result.ReviewersRequested++
+	log.Info("Requested reviews from %v on PR #%d (strategy: %s)", reviewers, pr.Number, cfg.Strategy)
+
+	// The cached PR may be shared, so the reminders of this run get a copy
+	updated := *pr
+	updated.RequestedReviewers = reviewers
+	return &updated
+}
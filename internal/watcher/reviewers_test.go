@@ -0,0 +1,75 @@
+package watcher
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+)
+
+func TestRequestReviewers_RoundRobinAcrossRuns(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.GitHub.Owner = "acme"
+	cfg.Actions.Reviewers = config.ReviewersConfig{
+		Enabled:  true,
+		Repos:    []string{"api"},
+		Strategy: "round_robin",
+		Team:     []string{"alice", "bob", "carol"},
+		Count:    1,
+	}
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeForge{}
+	w := NewPRWatcher(f, nil, nil, cfg)
+	w.SetStore(store)
+	thresholds := config.PRTimeRules{ApprovalTime: time.Hour, MergeTime: 24 * time.Hour}
+
+	run := func(pr *github.PullRequest) *github.PullRequest {
+		run := w.snapshot()
+		run.assigner = run.newReviewerAssigner(logger.Get(), []*github.PullRequest{pr}, true)
+		return run.requestReviewers(context.Background(), logger.Get(), &NotificationResult{}, pr, 2*time.Hour, thresholds)
+	}
+
+	// Authors are skipped, and each run continues after the last reviewer picked
+	run(&github.PullRequest{Number: 1, Owner: "acme", Repo: "api", User: &github.User{Login: "alice"}})
+	run(&github.PullRequest{Number: 2, Owner: "acme", Repo: "api", User: &github.User{Login: "carol"}})
+	updated := run(&github.PullRequest{Number: 3, Owner: "acme", Repo: "api", User: &github.User{Login: "dave"}})
+	run(&github.PullRequest{Number: 4, Owner: "acme", Repo: "web", User: &github.User{Login: "dave"}})
+
+	want := [][]string{{"bob"}, {"alice"}, {"bob"}}
+	if !reflect.DeepEqual(f.requested, want) {
+		t.Errorf("requested %v, want %v", f.requested, want)
+	}
+	if !reflect.DeepEqual(updated.RequestedReviewers, []string{"bob"}) {
+		t.Errorf("returned PR has requested reviewers %v, want [bob]", updated.RequestedReviewers)
+	}
+}
+
+func TestReviewerAssigner_LeastLoaded(t *testing.T) {
+	open := []*github.PullRequest{
+		{Number: 1, Owner: "acme", Repo: "api", RequestedReviewers: []string{"alice", "bob"}},
+		{Number: 2, Owner: "acme", Repo: "api", RequestedReviewers: []string{"Alice"}},
+	}
+	a, err := newReviewerAssigner(nil, open, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr := &github.PullRequest{Number: 3, Owner: "acme", Repo: "api", User: &github.User{Login: "dave"}}
+	if got := a.choose(pr, []string{"alice", "bob", "carol"}, 2, false); !reflect.DeepEqual(got, []string{"carol", "bob"}) {
+		t.Errorf("chose %v, want [carol bob]", got)
+	}
+	if err := a.record(pr, []string{"carol"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.choose(pr, []string{"alice", "carol"}, 1, false); !reflect.DeepEqual(got, []string{"carol"}) {
+		t.Errorf("chose %v, want [carol] with one PR against alice's two", got)
+	}
+}
@@ -0,0 +1,139 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+	"github.com/jimohabdol/git-pr-watcher/internal/snooze"
+)
+
+// snoozed applies the /pr-watcher commands found on pr and reports whether
+// its reminders are snoozed. A snooze ends early when the pull request gets
+// a new push or review.
+func (w *PRWatcher) snoozed(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest) bool {
+	cfg := w.config.Actions.Snooze
+	if !cfg.Enabled || w.snoozes == nil {
+		return false
+	}
+	key := prKey(pr)
+	current, ok := w.snoozes.Get(key)
+	changed, commanded := false, false
+
+	for _, event := range pr.Timeline {
+		if event.Command == "" || (ok && !event.CreatedAt.After(current.At)) {
+			continue
+		}
+		if len(cfg.Users) > 0 && !slices.ContainsFunc(cfg.Users, func(u string) bool { return strings.EqualFold(strings.TrimPrefix(u, "@"), event.Actor) }) {
+			log.Info("Ignoring %q on PR #%d from %s, who is not in actions.snooze.users", event.Command, pr.Number, event.Actor)
+			continue
+		}
+		cmd, err := snooze.ParseCommand(event.Command, cfg.Default)
+		if err != nil {
+			log.Info("Ignoring %q on PR #%d from %s: %v", event.Command, pr.Number, event.Actor, err)
+			continue
+		}
+		switch cmd.Action {
+		case "snooze":
+			until := event.CreatedAt.Add(min(cmd.Duration, cfg.Max))
+			if !time.Now().Before(until) {
+				continue
+			}
+			current, ok = snooze.Snooze{Until: until, At: event.CreatedAt, By: event.Actor}, true
+		case "unsnooze":
+			if !ok || current.Ended != "" {
+				continue
+			}
+			current.Ended = "unsnoozed by " + event.Actor
+			current.At = event.CreatedAt
+		}
+		changed, commanded = true, true
+	}
+	if !ok {
+		return false
+	}
+
+	if current.Ended == "" {
+		var head string
+		if pr.Head != nil {
+			head = pr.Head.SHA
+		}
+		switch {
+		case current.HeadSHA == "":
+			current.HeadSHA = head
+			changed = true
+		case current.HeadSHA != head:
+			current.Ended = "new push"
+			changed = true
+		}
+		for _, review := range pr.Reviews {
+			if current.Ended == "" && review.SubmittedAt.After(current.At) {
+				current.Ended = "new review by " + review.User
+				changed = true
+			}
+		}
+		if current.Ended != "" {
+			log.Info("Snooze of PR #%d by %s ended early: %s", pr.Number, current.By, current.Ended)
+		}
+	}
+
+	if changed {
+		if err := w.snoozes.Set(key, current); err != nil {
+			log.Error("Failed to save the snooze of PR #%d: %v", pr.Number, err)
+		}
+	}
+	if !current.Active(time.Now()) {
+		return false
+	}
+	if commanded {
+		w.commentSnoozed(ctx, log, result, pr, current)
+	}
+	result.Snoozed++
+	log.Info("Reminders for PR #%d are snoozed by %s until %s", pr.Number, current.By, current.Until.Format(time.RFC3339))
+	return true
+}
+
+// commentSnoozed confirms a snooze on the reminder comment, if comments are
+// enabled
+func (w *PRWatcher) commentSnoozed(ctx context.Context, log *logger.Logger, result *NotificationResult, pr *github.PullRequest, s snooze.Snooze) {
+	if !w.config.Actions.Comments.Enabled {
+		return
+	}
+	commenter, ok := w.forgeFor(pr).(forge.Commenter)
+	if !ok {
+		return
+	}
+	if w.config.Debug.SkipEmails {
+		log.Info("[SKIPPED] Would confirm the snooze on PR #%d", pr.Number)
+		return
+	}
+	body := notifier.SnoozedComment(s.Until, s.By)
+	if err := commenter.UpsertComment(ctx, pr.Owner, pr.Repo, pr.Number, notifier.CommentMarker, body); err != nil {
+		log.Error("Failed to confirm the snooze on PR #%d: %v", pr.Number, err)
+		result.Errors = append(result.Errors, fmt.Errorf("snooze comment for PR #%d: %w", pr.Number, err))
+		return
+	}
+	result.Comments++
+}
+
+// snoozeLinks returns the signed snooze links for the emails about pr, if
+// enabled
+func (w *PRWatcher) snoozeLinks(pr *github.PullRequest) []notifier.SnoozeLink {
+	cfg := w.config.Actions.Snooze
+	if !cfg.Enabled || !cfg.Links.Enabled {
+		return nil
+	}
+	base := strings.TrimRight(cfg.Links.BaseURL, "/") + cfg.Links.Path
+	expires := time.Now().Add(cfg.Links.ValidFor)
+	links := make([]notifier.SnoozeLink, 0, len(cfg.Links.Durations))
+	for _, d := range cfg.Links.Durations {
+		links = append(links, notifier.SnoozeLink{Duration: d, URL: snooze.Link(base, cfg.Links.Secret, prKey(pr), d, expires)})
+	}
+	return links
+}
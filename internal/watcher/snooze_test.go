@@ -0,0 +1,54 @@
+package watcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/snooze"
+)
+
+func TestSnoozed(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Actions.Snooze = config.SnoozeConfig{Enabled: true, Default: 24 * time.Hour, Max: 72 * time.Hour}
+	snoozes, err := snooze.NewStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewPRWatcher(&fakeForge{}, nil, nil, cfg)
+	w.SetSnoozes(snoozes)
+
+	commented := time.Now().Add(-time.Hour)
+	pr := &github.PullRequest{
+		Number: 7,
+		Owner:  "acme",
+		Repo:   "api",
+		Head:   &github.Branch{SHA: "abc"},
+		Timeline: []github.TimelineEvent{
+			{Type: "comment", Actor: "bob", CreatedAt: commented, Command: "/pr-watcher snooze 2w"},
+		},
+	}
+	snoozed := func(pr *github.PullRequest) bool {
+		return w.snoozed(context.Background(), logger.Get(), &NotificationResult{}, pr)
+	}
+
+	if !snoozed(pr) {
+		t.Fatal("PR with a snooze command is not snoozed")
+	}
+	if s, _ := snoozes.Get(prKey(pr)); !s.Until.Equal(commented.Add(72*time.Hour)) || s.By != "bob" {
+		t.Errorf("snooze = %+v, want bob's, capped at 72h", s)
+	}
+	if !snoozed(pr) {
+		t.Fatal("snooze did not last until the next run")
+	}
+
+	// A push ends the snooze, and the old command is not applied again
+	pushed := *pr
+	pushed.Head = &github.Branch{SHA: "def"}
+	if snoozed(&pushed) || snoozed(&pushed) {
+		t.Error("PR is still snoozed after a push")
+	}
+}
@@ -2,47 +2,145 @@ package watcher
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
 	"github.com/jimohabdol/git-pr-watcher/internal/github"
 	"github.com/jimohabdol/git-pr-watcher/internal/logger"
 	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+	"github.com/jimohabdol/git-pr-watcher/internal/snooze"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+	"github.com/jimohabdol/git-pr-watcher/internal/webhook"
 )
 
 type PRWatcher struct {
-	githubClient *github.Client
+	mu           sync.RWMutex
+	githubClient forge.Forge            // nil when GitHub is not configured
+	sources      map[string]forge.Forge // Forges of config.Sources, by name
 	notifier     *notifier.EmailNotifier
 	config       *config.Config
-	ctx          context.Context
-	cancel       context.CancelFunc
+	repos        *repoCache
+	cache        *webhook.Cache // Open PRs kept current by webhooks, nil when disabled
+	store        *state.Store   // State kept across runs, nil keeps it for one run only
+	snoozes      *snooze.Store  // Snoozed pull requests, nil when snoozing is unavailable
+
+	assigner *reviewerAssigner // Reviewer assignment of the current run
+	drafts   *draftTracker     // Draft lifecycle of the current run
 }
 
 type NotificationResult struct {
-	ApprovalReminders int
-	MergeReminders    int
-	Escalations       int
-	DraftOverdue      int
-	Errors            []error
+	ApprovalReminders  int
+	MergeReminders     int
+	Escalations        int
+	DraftOverdue       int
+	WaitingReminders   int // Reminders to the reviewers or author a PR is waiting on
+	CIFailing          int // Authors of approved PRs asked to fix failing checks
+	Comments           int // Reminder comments created or updated
+	Labeled            int // Pull requests whose labels were updated
+	ReviewersRequested int // Pull requests reviewers were requested on
+	DraftsWarned       int // Stale drafts warned about
+	DraftsClosed       int // Stale drafts closed
+	Snoozed            int // Pull requests whose reminders are snoozed
+	Errors             []error
+}
+
+// Sent returns the number of notifications sent
+func (r *NotificationResult) Sent() int {
+	return r.ApprovalReminders + r.MergeReminders + r.Escalations + r.DraftOverdue + r.WaitingReminders + r.CIFailing
+}
+
+// RunError is returned by CheckPRs when no repository could be fetched, or
+// when more notifications failed than health.failure_threshold allows.
+// Repositories that fail while others are fetched are only reported.
+type RunError struct {
+	Repos       int     // Repositories checked
+	FailedRepos int     // Repositories whose pull requests could not be fetched
+	FetchErrors []error // Errors of those repositories and of repository discovery
+	Processed   int     // Notifications attempted
+	Failed      int     // Notifications that failed
+	Threshold   int
+	Errors      []error // Notification failures
+}
+
+func (e *RunError) Error() string {
+	return fmt.Sprintf("%d of %d repositories could not be fetched and %d of %d notifications failed (threshold: %d)",
+		e.FailedRepos, e.Repos, e.Failed, e.Processed, e.Threshold)
 }
 
-func NewPRWatcher(githubClient *github.Client, notifier *notifier.EmailNotifier, cfg *config.Config) *PRWatcher {
-	ctx, cancel := context.WithCancel(context.Background())
+func (e *RunError) Unwrap() []error {
+	return append(append([]error{}, e.FetchErrors...), e.Errors...)
+}
+
+func NewPRWatcher(githubClient forge.Forge, sources map[string]forge.Forge, notifier *notifier.EmailNotifier, cfg *config.Config) *PRWatcher {
 	return &PRWatcher{
 		githubClient: githubClient,
+		sources:      sources,
 		notifier:     notifier,
 		config:       cfg,
-		ctx:          ctx,
-		cancel:       cancel,
+		repos:        &repoCache{},
 	}
 }
 
-func (w *PRWatcher) Close() {
-	if w.cancel != nil {
-		w.cancel()
+// Reload atomically replaces the configuration and dependencies used by
+// subsequent runs. Runs already in progress finish with the previous ones.
+func (w *PRWatcher) Reload(cfg *config.Config, githubClient forge.Forge, sources map[string]forge.Forge, notifier *notifier.EmailNotifier) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.config = cfg
+	w.githubClient = githubClient
+	w.sources = sources
+	w.notifier = notifier
+	w.repos.reset()
+}
+
+// snapshot returns a watcher bound to the current configuration so that a
+// run sees a consistent view even if Reload is called while it is running
+func (w *PRWatcher) snapshot() *PRWatcher {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return &PRWatcher{
+		githubClient: w.githubClient,
+		sources:      w.sources,
+		notifier:     w.notifier,
+		config:       w.config,
+		repos:        w.repos,
+		cache:        w.cache,
+		store:        w.store,
+		snoozes:      w.snoozes,
 	}
+}
+
+// SetCache makes runs read the open PRs of cached repositories from cache
+// instead of the GitHub API, except for the periodic full reconciliation
+func (w *PRWatcher) SetCache(cache *webhook.Cache) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cache = cache
+}
+
+// SetStore makes runs keep state, such as reviewer assignments, in store
+func (w *PRWatcher) SetStore(store *state.Store) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.store = store
+}
+
+// SetSnoozes makes runs respect, and record, the snoozes in snoozes
+func (w *PRWatcher) SetSnoozes(snoozes *snooze.Store) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.snoozes = snoozes
+}
+
+func (w *PRWatcher) Close() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	if w.notifier != nil {
 		w.notifier.Close()
 	}
@@ -74,105 +172,210 @@ func (w *PRWatcher) getTimeThresholds(pr *github.PullRequest) config.PRTimeRules
 	}
 }
 
-func (w *PRWatcher) processPR(pr *github.PullRequest) *NotificationResult {
+func (w *PRWatcher) processPR(ctx context.Context, log *logger.Logger, pr *github.PullRequest) *NotificationResult {
+	log = log.With(logger.FieldRepo, pr.FullName(), logger.FieldPR, pr.Number)
 	result := &NotificationResult{}
 	age := time.Since(pr.CreatedAt)
 	thresholds := w.getTimeThresholds(pr)
+	w.label(ctx, log, result, pr, age, thresholds)
+	if w.snoozed(ctx, log, result, pr) {
+		return result
+	}
+	pr = w.requestReviewers(ctx, log, result, pr, age, thresholds)
+	if w.draftLifecycle(ctx, log, result, pr) {
+		return result
+	}
 
 	if pr.Draft {
 		if age >= thresholds.DraftTime {
-			logger.Debug("Draft PR #%d is overdue (age: %v, threshold: %v, size: %s)",
+			log := log.With(logger.FieldNotificationType, notifier.DraftOverdue.String())
+			log.Debug("Draft PR #%d is overdue (age: %v, threshold: %v, size: %s)",
 				pr.Number, age, thresholds.DraftTime, pr.SizeCategory)
 
-			if err := w.notifier.SendDraftOverdue(pr, age, thresholds.DraftTime); err != nil {
-				logger.Error("Failed to send draft overdue notification for PR #%d: %v", pr.Number, err)
+			if err := w.notifier.SendDraftOverdue(ctx, pr, age, thresholds.DraftTime, w.snoozeLinks(pr)...); err != nil {
+				log.Error("Failed to send draft overdue notification for PR #%d: %v", pr.Number, err)
 				result.Errors = append(result.Errors, fmt.Errorf("draft overdue for PR #%d: %w", pr.Number, err))
 			} else {
 				result.DraftOverdue++
-				logger.Info("Sent draft overdue notification for PR #%d", pr.Number)
+				log.Info("Sent draft overdue notification for PR #%d", pr.Number)
 			}
 		}
 		return result
 	}
 
 	if age >= thresholds.MergeTime {
-		logger.Debug("PR #%d needs escalation (age: %v, threshold: %v, size: %s)",
+		log := log.With(logger.FieldNotificationType, notifier.Escalation.String())
+		log.Debug("PR #%d needs escalation (age: %v, threshold: %v, size: %s)",
 			pr.Number, age, thresholds.MergeTime, pr.SizeCategory)
 
-		if err := w.notifier.SendEscalation(pr, age, thresholds.MergeTime, w.config.Rules.EscalationEmail); err != nil {
-			logger.Error("Failed to send escalation for PR #%d: %v", pr.Number, err)
+		if err := w.notifier.SendEscalation(ctx, pr, age, thresholds.MergeTime, w.config.Rules.EscalationEmail, w.snoozeLinks(pr)...); err != nil {
+			log.Error("Failed to send escalation for PR #%d: %v", pr.Number, err)
 			result.Errors = append(result.Errors, fmt.Errorf("escalation for PR #%d: %w", pr.Number, err))
 		} else {
 			result.Escalations++
-			logger.Info("Sent escalation for PR #%d", pr.Number)
+			log.Info("Sent escalation for PR #%d", pr.Number)
 		}
+		w.comment(ctx, log, result, notifier.Escalation, pr, thresholds.MergeTime)
+		return result
+	}
+
+	// With inactivity rules, PRs without sufficient approvals are reminded
+	// by how long they have waited rather than by age
+	if pr.ReviewCount < 2 && w.config.Rules.Inactivity.Enabled() {
+		w.remindWaiting(ctx, log, result, pr)
 		return result
 	}
 
 	// PRs without sufficient approvals need approval reminder
 	if pr.ReviewCount < 2 && age >= thresholds.ApprovalTime {
-		logger.Debug("PR #%d needs approval reminder (age: %v, threshold: %v, reviews: %d, size: %s)",
+		log := log.With(logger.FieldNotificationType, notifier.ApprovalReminder.String())
+		log.Debug("PR #%d needs approval reminder (age: %v, threshold: %v, reviews: %d, size: %s)",
 			pr.Number, age, thresholds.ApprovalTime, pr.ReviewCount, pr.SizeCategory)
 
-		if err := w.notifier.SendApprovalReminder(pr, age, thresholds.ApprovalTime); err != nil {
-			logger.Error("Failed to send approval reminder for PR #%d: %v", pr.Number, err)
+		if err := w.notifier.SendApprovalReminder(ctx, pr, age, thresholds.ApprovalTime, w.snoozeLinks(pr)...); err != nil {
+			log.Error("Failed to send approval reminder for PR #%d: %v", pr.Number, err)
 			result.Errors = append(result.Errors, fmt.Errorf("approval reminder for PR #%d: %w", pr.Number, err))
 		} else {
 			result.ApprovalReminders++
-			logger.Info("Sent approval reminder for PR #%d", pr.Number)
+			log.Info("Sent approval reminder for PR #%d", pr.Number)
 		}
+		w.comment(ctx, log, result, notifier.ApprovalReminder, pr, thresholds.ApprovalTime)
 		return result
 	}
 
-	// PRs with sufficient approvals need merge reminder
+	// PRs with sufficient approvals need merge reminder, unless their checks
+	// are still running or the author has to fix them first
 	if pr.ReviewCount >= 2 && age >= thresholds.MergeReminderTime {
-		logger.Debug("PR #%d needs merge reminder (age: %v, threshold: %v, reviews: %d, size: %s)",
+		if missing := pr.MissingChecks(); len(missing) > 0 {
+			log.Warn("Required checks %v never reported on PR #%d, ignoring them", missing, pr.Number)
+		}
+		switch pr.CIState() {
+		case forge.CIPending:
+			log.Debug("PR #%d is approved but its checks are still running", pr.Number)
+			return result
+		case forge.CIFailing:
+			log := log.With(logger.FieldNotificationType, notifier.CIFailing.String())
+			log.Debug("PR #%d is approved but its checks fail (age: %v, failing: %v)", pr.Number, age, pr.FailingChecks())
+
+			if err := w.notifier.SendCIFailing(ctx, pr, age, thresholds.MergeReminderTime, w.snoozeLinks(pr)...); err != nil {
+				log.Error("Failed to send CI failing notification for PR #%d: %v", pr.Number, err)
+				result.Errors = append(result.Errors, fmt.Errorf("ci failing for PR #%d: %w", pr.Number, err))
+			} else {
+				result.CIFailing++
+				log.Info("Sent CI failing notification for PR #%d", pr.Number)
+			}
+			w.comment(ctx, log, result, notifier.CIFailing, pr, thresholds.MergeReminderTime)
+			return result
+		}
+
+		log := log.With(logger.FieldNotificationType, notifier.MergeReminder.String())
+		log.Debug("PR #%d needs merge reminder (age: %v, threshold: %v, reviews: %d, size: %s)",
 			pr.Number, age, thresholds.MergeReminderTime, pr.ReviewCount, pr.SizeCategory)
 
-		if err := w.notifier.SendMergeReminder(pr, age, thresholds.MergeReminderTime); err != nil {
-			logger.Error("Failed to send merge reminder for PR #%d: %v", pr.Number, err)
+		if err := w.notifier.SendMergeReminder(ctx, pr, age, thresholds.MergeReminderTime, w.snoozeLinks(pr)...); err != nil {
+			log.Error("Failed to send merge reminder for PR #%d: %v", pr.Number, err)
 			result.Errors = append(result.Errors, fmt.Errorf("merge reminder for PR #%d: %w", pr.Number, err))
 		} else {
 			result.MergeReminders++
-			logger.Info("Sent merge reminder for PR #%d", pr.Number)
+			log.Info("Sent merge reminder for PR #%d", pr.Number)
 		}
+		w.comment(ctx, log, result, notifier.MergeReminder, pr, thresholds.MergeReminderTime)
 	}
 
 	return result
 }
 
-func (w *PRWatcher) CheckPRs() error {
-	logger.Info("Checking PRs for repositories: %v", w.config.GitHub.Repos)
+// newRunID returns a short random identifier used to correlate the log
+// records of a single CheckPRs run
+func newRunID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// CheckPRs fetches the open PRs and sends the notifications that are due.
+// Canceling ctx stops the run between API calls and emails.
+func (w *PRWatcher) CheckPRs(ctx context.Context) error {
+	return w.snapshot().checkPRs(ctx)
+}
 
-	prs, err := w.githubClient.GetPullRequests(w.config.GitHub.Owner, w.config.GitHub.Repos)
-	if err != nil {
-		return fmt.Errorf("failed to fetch pull requests: %w", err)
+func (w *PRWatcher) checkPRs(ctx context.Context) error {
+	log := logger.With(logger.FieldRunID, newRunID())
+	fetched := w.fetchPullRequests(ctx, log)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("run canceled: %w", err)
 	}
+	prs := fetched.prs
+	w.assigner = w.newReviewerAssigner(log, prs, len(fetched.errors) == 0)
+	w.drafts = w.newDraftTracker(log, prs, len(fetched.errors) == 0)
 
-	logger.Info("Found %d open pull requests", len(prs))
-	logger.Info("Processing %d open PRs (including drafts)", len(prs))
+	log.Info("Found %d open pull requests", len(prs))
+	log.Info("Processing %d open PRs (including drafts)", len(prs))
 
 	concurrency := w.config.Debug.Concurrency
 	if concurrency <= 0 {
 		concurrency = 5
 	}
 
-	results := w.processPRsConcurrently(prs, concurrency)
+	results := w.processPRsConcurrently(ctx, log, prs, concurrency)
+	if err := ctx.Err(); err != nil {
+		log.Info("Run canceled after %d notifications", results.Sent())
+		return fmt.Errorf("run canceled: %w", err)
+	}
 
-	logger.Info("Completed processing: %d approval reminders, %d merge reminders, %d escalations, and %d draft overdue notifications sent",
+	log.Info("Completed processing: %d approval reminders, %d merge reminders, %d escalations, and %d draft overdue notifications sent",
 		results.ApprovalReminders, results.MergeReminders, results.Escalations, results.DraftOverdue)
+	if results.WaitingReminders > 0 {
+		log.Info("Sent %d reminders to the reviewers or authors PRs are waiting on", results.WaitingReminders)
+	}
+	if results.CIFailing > 0 {
+		log.Info("Asked the authors of %d approved PRs to fix failing checks", results.CIFailing)
+	}
+	if results.Comments > 0 {
+		log.Info("Updated %d reminder comments", results.Comments)
+	}
+	if results.Labeled > 0 {
+		log.Info("Updated the labels of %d PRs", results.Labeled)
+	}
+	if results.ReviewersRequested > 0 {
+		log.Info("Requested reviewers on %d PRs", results.ReviewersRequested)
+	}
+	if results.DraftsWarned+results.DraftsClosed > 0 {
+		log.Info("Warned about %d stale drafts and closed %d", results.DraftsWarned, results.DraftsClosed)
+	}
+	if results.Snoozed > 0 {
+		log.Info("Skipped %d snoozed PRs", results.Snoozed)
+	}
+
+	sent := results.Sent()
+	log.Info("Run report: %d of %d repositories fetched (%d fetch errors), %d PRs processed, %d notifications sent (%d notification errors)",
+		fetched.repos-fetched.failed, fetched.repos, len(fetched.errors), len(prs), sent, len(results.Errors))
 
 	if len(results.Errors) > 0 {
-		logger.Error("Encountered %d errors during processing", len(results.Errors))
+		log.Error("Encountered %d errors during processing", len(results.Errors))
 		for _, err := range results.Errors {
-			logger.Error("Error: %v", err)
+			log.Error("Notification error: %v", err)
+		}
+	}
+
+	if fetched.allFailed() || len(results.Errors) > w.config.Health.FailureThreshold {
+		return &RunError{
+			Repos:       fetched.repos,
+			FailedRepos: fetched.failed,
+			FetchErrors: fetched.errors,
+			Processed:   sent + len(results.Errors),
+			Failed:      len(results.Errors),
+			Threshold:   w.config.Health.FailureThreshold,
+			Errors:      results.Errors,
 		}
 	}
 
 	return nil
 }
 
-func (w *PRWatcher) processPRsConcurrently(prs []*github.PullRequest, concurrency int) *NotificationResult {
+func (w *PRWatcher) processPRsConcurrently(ctx context.Context, log *logger.Logger, prs []*github.PullRequest, concurrency int) *NotificationResult {
 	prChan := make(chan *github.PullRequest, len(prs))
 	resultChan := make(chan *NotificationResult, len(prs))
 
@@ -183,10 +386,10 @@ func (w *PRWatcher) processPRsConcurrently(prs []*github.PullRequest, concurrenc
 			defer wg.Done()
 			for pr := range prChan {
 				select {
-				case <-w.ctx.Done():
+				case <-ctx.Done():
 					return
 				default:
-					resultChan <- w.processPR(pr)
+					resultChan <- w.processPR(ctx, log, pr)
 				}
 			}
 		}()
@@ -195,10 +398,10 @@ func (w *PRWatcher) processPRsConcurrently(prs []*github.PullRequest, concurrenc
 	go func() {
 		defer close(prChan)
 		for i, pr := range prs {
-			logger.Progress("Processing PR %d/%d: #%d", i+1, len(prs), pr.Number)
+			log.Progress("Processing PR %d/%d: #%d", i+1, len(prs), pr.Number)
 			select {
 			case prChan <- pr:
-			case <-w.ctx.Done():
+			case <-ctx.Done():
 				return
 			}
 		}
@@ -207,7 +410,7 @@ func (w *PRWatcher) processPRsConcurrently(prs []*github.PullRequest, concurrenc
 	go func() {
 		wg.Wait()
 		close(resultChan)
-		logger.ProgressEnd()
+		log.ProgressEnd()
 	}()
 
 	totalResult := &NotificationResult{}
@@ -216,21 +419,38 @@ func (w *PRWatcher) processPRsConcurrently(prs []*github.PullRequest, concurrenc
 		totalResult.MergeReminders += result.MergeReminders
 		totalResult.Escalations += result.Escalations
 		totalResult.DraftOverdue += result.DraftOverdue
+		totalResult.WaitingReminders += result.WaitingReminders
+		totalResult.CIFailing += result.CIFailing
+		totalResult.Comments += result.Comments
+		totalResult.Labeled += result.Labeled
+		totalResult.ReviewersRequested += result.ReviewersRequested
+		totalResult.DraftsWarned += result.DraftsWarned
+		totalResult.DraftsClosed += result.DraftsClosed
+		totalResult.Snoozed += result.Snoozed
 		totalResult.Errors = append(totalResult.Errors, result.Errors...)
 	}
 
 	return totalResult
 }
 
-func (w *PRWatcher) CheckSpecificPR(repo string, prNumber int) error {
+// CheckSpecificPR processes a single PR. repo may be "owner/repo"; a bare
+// name belongs to github.owner.
+func (w *PRWatcher) CheckSpecificPR(ctx context.Context, repo string, prNumber int) error {
+	w = w.snapshot()
 	logger.Info("Checking specific PR #%d in repository %s", prNumber, repo)
 
-	pr, err := w.githubClient.GetPRDetails(w.config.GitHub.Owner, repo, prNumber)
+	if w.githubClient == nil {
+		return fmt.Errorf("GitHub is not configured")
+	}
+	owner, name := config.SplitRepo(w.config.GitHub.Owner, repo)
+	pr, err := w.githubClient.GetPRDetails(ctx, owner, name, prNumber)
 	if err != nil {
 		return fmt.Errorf("failed to fetch PR details: %w", err)
 	}
 
-	result := w.processPR(pr)
+	w.assigner = w.newReviewerAssigner(logger.Get(), []*github.PullRequest{pr}, false)
+	w.drafts = w.newDraftTracker(logger.Get(), []*github.PullRequest{pr}, false)
+	result := w.processPR(ctx, logger.Get(), pr)
 
 	logger.Info("Completed processing PR #%d: %d approval reminders, %d merge reminders, %d escalations, and %d draft overdue notifications sent",
 		prNumber, result.ApprovalReminders, result.MergeReminders, result.Escalations, result.DraftOverdue)
@@ -242,11 +462,13 @@ func (w *PRWatcher) CheckSpecificPR(repo string, prNumber int) error {
 	return nil
 }
 
-func (w *PRWatcher) GetPRSummary() (*PRSummary, error) {
-	prs, err := w.githubClient.GetPullRequests(w.config.GitHub.Owner, w.config.GitHub.Repos)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch pull requests: %w", err)
+func (w *PRWatcher) GetPRSummary(ctx context.Context) (*PRSummary, error) {
+	w = w.snapshot()
+	fetched := w.fetchPullRequests(ctx, logger.Get())
+	if fetched.allFailed() {
+		return nil, fmt.Errorf("failed to fetch pull requests: %w", errors.Join(fetched.errors...))
 	}
+	prs := fetched.prs
 
 	summary := &PRSummary{
 		TotalPRs:        len(prs),
@@ -256,6 +478,9 @@ func (w *PRWatcher) GetPRSummary() (*PRSummary, error) {
 		Draft:           0,
 		PRs:             []PRStatus{},
 	}
+	for _, err := range fetched.errors {
+		summary.FetchErrors = append(summary.FetchErrors, err.Error())
+	}
 
 	now := time.Now()
 
@@ -267,12 +492,17 @@ func (w *PRWatcher) GetPRSummary() (*PRSummary, error) {
 			Number:      pr.Number,
 			Title:       pr.Title,
 			Repo:        pr.Repo,
+			Owner:       pr.Owner,
+			Source:      pr.Source,
 			Author:      pr.User.Login,
 			Age:         age,
 			Approved:    pr.Approved,
 			ReviewCount: pr.ReviewCount,
 			URL:         pr.URL,
 		}
+		who, since := pr.BallInCourt()
+		status.WaitingOn, status.Waiting = who, now.Sub(since)
+		status.CI = pr.CIState()
 
 		if pr.Draft {
 			summary.Draft++
@@ -283,7 +513,14 @@ func (w *PRWatcher) GetPRSummary() (*PRSummary, error) {
 			}
 		} else if pr.ReviewCount >= 2 {
 			summary.Approved++
-			status.Status = "Approved"
+			switch status.CI {
+			case forge.CIPending:
+				status.Status = "Approved, CI Pending"
+			case forge.CIFailing:
+				status.Status = "Approved, CI Failing"
+			default:
+				status.Status = "Approved"
+			}
 		} else if age >= thresholds.MergeTime {
 			summary.NeedsEscalation++
 			status.Status = "Needs Escalation"
@@ -307,16 +544,22 @@ type PRSummary struct {
 	Approved        int        `json:"approved"`
 	Draft           int        `json:"draft"`
 	PRs             []PRStatus `json:"prs"`
+	FetchErrors     []string   `json:"fetch_errors,omitempty"` // Repositories left out of the summary
 }
 
 type PRStatus struct {
 	Number      int           `json:"number"`
 	Title       string        `json:"title"`
 	Repo        string        `json:"repo"`
+	Owner       string        `json:"owner"`
+	Source      string        `json:"source,omitempty"`
 	Author      string        `json:"author"`
 	Age         time.Duration `json:"age"`
 	Approved    bool          `json:"approved"`
 	ReviewCount int           `json:"review_count"`
 	Status      string        `json:"status"`
+	WaitingOn   string        `json:"waiting_on"`   // reviewer, author, ci or merge
+	Waiting     time.Duration `json:"waiting"`      // How long it has been their turn
+	CI          string        `json:"ci,omitempty"` // pending, failing or passing; empty when unknown
 	URL         string        `json:"url"`
 }
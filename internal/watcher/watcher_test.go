@@ -1,11 +1,16 @@
 package watcher
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
 	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
 )
 
 func TestPRWatcher_GetPRSummary(t *testing.T) {
@@ -113,3 +118,111 @@ func TestPRStatus_Status(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessPR_CIGatesMergeReminder(t *testing.T) {
+	cfg := &config.Config{
+		Rules: config.RulesConfig{ApprovalTime: time.Hour, MergeReminderTime: 2 * time.Hour, MergeTime: 72 * time.Hour},
+	}
+	emailNotifier, err := notifier.NewEmailNotifier(cfg.Email, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewPRWatcher(&fakeForge{}, nil, emailNotifier, cfg)
+
+	for _, tc := range []struct {
+		name           string
+		checks         []github.Check
+		merge, failing int
+	}{
+		{"no checks", nil, 1, 0},
+		{"passing", []github.Check{{Name: "build", State: forge.CIPassing}}, 1, 0},
+		{"pending", []github.Check{{Name: "build", State: forge.CIPassing}, {Name: "test", State: forge.CIPending}}, 0, 0},
+		{"failing", []github.Check{{Name: "build", State: forge.CIFailing}, {Name: "test", State: forge.CIPending}}, 0, 1},
+		{"required pending", []github.Check{{Name: "lint", State: forge.CIFailing}, {Name: "build", State: forge.CIPending, Required: true}}, 0, 0},
+		{"required never reported", []github.Check{{Name: "lint", State: forge.CIPassing},
+			{Name: "jenkins", State: forge.CIPending, Required: true, Since: time.Now().Add(-2 * time.Hour), Timeout: time.Hour}}, 1, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pr := &github.PullRequest{Owner: "acme", Repo: "api", Number: 7, CreatedAt: time.Now().Add(-3 * time.Hour), ReviewCount: 2, Checks: tc.checks}
+			result := w.processPR(context.Background(), logger.Get(), pr)
+			if result.MergeReminders != tc.merge || result.CIFailing != tc.failing || len(result.Errors) > 0 {
+				t.Errorf("got %d merge reminders and %d CI failing notifications (errors: %v), want %d and %d",
+					result.MergeReminders, result.CIFailing, result.Errors, tc.merge, tc.failing)
+			}
+		})
+	}
+}
+
+// failingForge fails every request
+type failingForge struct {
+	forge.Forge
+}
+
+func (f *failingForge) GetPullRequests(ctx context.Context, owner string, repos []string) ([]*forge.PullRequest, error) {
+	return nil, errors.New("bad credentials")
+}
+
+func (f *failingForge) ListRepositories(ctx context.Context, owner string) ([]*forge.Repository, error) {
+	return nil, errors.New("bad credentials")
+}
+
+func TestFetchPullRequests_CountsFailures(t *testing.T) {
+	cfg := &config.Config{GitHub: config.GitHubConfig{Owner: "acme", Repos: []string{"api", "web"}}}
+	w := NewPRWatcher(&failingForge{}, nil, nil, cfg)
+
+	// A failure of the whole owner counts against each of its repositories
+	fetched := w.fetchPullRequests(context.Background(), logger.Get())
+	if fetched.repos != 2 || fetched.failed != 2 || len(fetched.errors) != 2 {
+		t.Errorf("got %d repos, %d failed and %d errors, want 2 of each", fetched.repos, fetched.failed, len(fetched.errors))
+	}
+	if _, err := w.GetPRSummary(context.Background()); err == nil {
+		t.Error("Expected GetPRSummary to fail when no repository could be fetched")
+	}
+
+	// So does a discovery that finds nothing to fetch
+	cfg = &config.Config{GitHub: config.GitHubConfig{Owner: "acme", Discovery: config.DiscoveryConfig{Enabled: true}}}
+	w = NewPRWatcher(&failingForge{}, nil, nil, cfg)
+	fetched = w.fetchPullRequests(context.Background(), logger.Get())
+	if fetched.repos != 0 || len(fetched.errors) != 1 {
+		t.Errorf("got %d repos and %d errors, want the discovery error only", fetched.repos, len(fetched.errors))
+	}
+	if _, err := w.GetPRSummary(context.Background()); err == nil {
+		t.Error("Expected GetPRSummary to fail when discovery failed")
+	}
+}
+
+// partialForge fails to fetch the repositories in failing
+type partialForge struct {
+	forge.Forge
+	failing string
+}
+
+func (f *partialForge) GetPullRequests(ctx context.Context, owner string, repos []string) ([]*forge.PullRequest, error) {
+	var failed forge.FetchErrors
+	for _, repo := range repos {
+		if repo == f.failing {
+			failed = append(failed, &forge.RepoError{Owner: owner, Repo: repo, Err: errors.New("not found")})
+		}
+	}
+	if len(failed) > 0 {
+		return nil, failed
+	}
+	return nil, nil
+}
+
+func TestCheckPRs_ToleratesFailedRepositories(t *testing.T) {
+	cfg := &config.Config{GitHub: config.GitHubConfig{Owner: "acme", Repos: []string{"api", "old"}}}
+
+	// A repository that cannot be fetched is reported, not a failed run
+	w := NewPRWatcher(&partialForge{failing: "old"}, nil, nil, cfg)
+	if err := w.CheckPRs(context.Background()); err != nil {
+		t.Errorf("Expected one failed repository to be tolerated, got %v", err)
+	}
+
+	// When nothing can be fetched, the run fails
+	w = NewPRWatcher(&failingForge{}, nil, nil, cfg)
+	var runErr *RunError
+	if err := w.CheckPRs(context.Background()); !errors.As(err, &runErr) || runErr.FailedRepos != 2 {
+		t.Errorf("Expected a RunError for 2 failed repositories, got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package webhook
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+)
+
+// stateKey is the state store key the cache is persisted under
+const stateKey = "webhook.pull_requests"
+
+// Cache holds the open pull requests of the watched repositories. A
+// repository enters the cache when a run fetches it in full and is then
+// kept current by webhook events, so later runs can skip the API for it.
+// Cached PRs are never modified in place; events replace them, which makes
+// the PRs handed out safe to read without holding the lock.
+type Cache struct {
+	mu         sync.RWMutex
+	store      *state.Store
+	repos      map[string]*cachedRepo // keyed by lower-cased owner/repo
+	reconciled time.Time
+}
+
+type cachedRepo struct {
+	PullRequests map[int]*github.PullRequest `json:"pull_requests"`
+	Synced       time.Time                   `json:"synced"` // Last full fetch
+}
+
+// persistedCache is the form the cache takes in the state store
+type persistedCache struct {
+	Repos      map[string]*cachedRepo `json:"repos"`
+	Reconciled time.Time              `json:"reconciled"`
+}
+
+// NewCache returns a cache backed by store, loading what a previous process
+// left there. A nil store keeps the cache in memory only.
+func NewCache(store *state.Store) (*Cache, error) {
+	c := &Cache{store: store, repos: make(map[string]*cachedRepo)}
+	if store == nil {
+		return c, nil
+	}
+
+	var saved persistedCache
+	if _, err := store.Get(stateKey, &saved); err != nil {
+		return nil, err
+	}
+	if saved.Repos != nil {
+		c.repos = saved.Repos
+	}
+	c.reconciled = saved.Reconciled
+	return c, nil
+}
+
+func repoKey(owner, repo string) string {
+	return strings.ToLower(owner + "/" + repo)
+}
+
+// PullRequests returns the cached open PRs of the given repositories of
+// owner, and the repositories that are not cached and must be fetched
+func (c *Cache) PullRequests(owner string, repos []string) ([]*github.PullRequest, []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var (
+		prs     []*github.PullRequest
+		missing []string
+	)
+	for _, repo := range repos {
+		cached, ok := c.repos[repoKey(owner, repo)]
+		if !ok {
+			missing = append(missing, repo)
+			continue
+		}
+		for _, pr := range cached.PullRequests {
+			prs = append(prs, pr)
+		}
+	}
+	slices.SortFunc(prs, func(a, b *github.PullRequest) int {
+		if n := strings.Compare(a.FullName(), b.FullName()); n != 0 {
+			return n
+		}
+		return a.Number - b.Number
+	})
+	return prs, missing
+}
+
+// Replace stores the result of fetching the open PRs of repos of owner in
+// full, discarding what was cached for them
+func (c *Cache) Replace(owner string, repos []string, prs []*github.PullRequest) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, repo := range repos {
+		c.repos[repoKey(owner, repo)] = &cachedRepo{PullRequests: make(map[int]*github.PullRequest), Synced: now}
+	}
+	for _, pr := range prs {
+		if cached, ok := c.repos[repoKey(pr.Owner, pr.Repo)]; ok {
+			cached.PullRequests[pr.Number] = pr
+		}
+	}
+	return c.save()
+}
+
+// ReconcileDue reports whether the last full reconciliation is older than
+// interval
+func (c *Cache) ReconcileDue(interval time.Duration) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Since(c.reconciled) >= interval
+}
+
+// MarkReconciled records a full reconciliation that started at started.
+// Repositories not fetched since then are no longer watched, or failed, and
+// are dropped so they are fetched again when needed.
+func (c *Cache) MarkReconciled(started time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, cached := range c.repos {
+		if cached.Synced.Before(started) {
+			delete(c.repos, key)
+		}
+	}
+	c.reconciled = started
+	return c.save()
+}
+
+// Apply updates the cache with a webhook event and reports whether it
+// changed anything. Events for repositories that are not cached are ignored;
+// the next run fetches those in full.
+func (c *Cache) Apply(event *github.WebhookEvent) (bool, error) {
+	if event.PullRequest == nil && event.Comment == nil && event.SHA == "" {
+		return false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := repoKey(event.Owner, event.Repo)
+	cached, ok := c.repos[key]
+	if !ok {
+		return false, nil
+	}
+
+	if event.SHA != "" {
+		return c.applyCheck(key, cached, event)
+	}
+
+	if event.PullRequest == nil {
+		prev, ok := cached.PullRequests[event.Number]
+		if !ok {
+			return false, nil
+		}
+		pr := *prev
+		pr.Timeline = capTimeline(append(slices.Clone(prev.Timeline), *event.Comment))
+		cached.PullRequests[event.Number] = &pr
+		return true, c.save()
+	}
+
+	number := event.PullRequest.Number
+	if event.PullRequest.State != "open" {
+		if _, ok := cached.PullRequests[number]; !ok {
+			return false, nil
+		}
+		delete(cached.PullRequests, number)
+		return true, c.save()
+	}
+
+	cached.PullRequests[number] = merge(cached.PullRequests[number], event)
+	return true, c.save()
+}
+
+// merge builds the new state of a PR from its cached state and an event.
+// Payloads carry the PR itself but not its reviews, and review payloads
+// lack the diff stats, so those are carried over from the cached PR. So are
+// its checks, until a push changes its head commit and the required ones
+// are pending again.
+func merge(prev *github.PullRequest, event *github.WebhookEvent) *github.PullRequest {
+	pr := *event.PullRequest
+	var reviews []github.Review
+	if prev != nil {
+		reviews = slices.Clone(prev.Reviews)
+		pr.Timeline = slices.Clone(prev.Timeline)
+		if pr.Head != nil && prev.Head != nil && pr.Head.SHA == prev.Head.SHA {
+			pr.Checks = slices.Clone(prev.Checks)
+		} else {
+			pr.Checks = pendingRequired(prev.Checks, event.At)
+		}
+		if pr.Additions+pr.Deletions+pr.ChangedFiles == 0 {
+			pr.Additions, pr.Deletions, pr.TotalChanges = prev.Additions, prev.Deletions, prev.TotalChanges
+			pr.ChangedFiles, pr.SizeCategory = prev.ChangedFiles, prev.SizeCategory
+		}
+	}
+
+	switch {
+	case event.Event == "pull_request_review" && event.Review != nil:
+		review := *event.Review
+		i := slices.IndexFunc(reviews, func(r github.Review) bool { return r.ID == review.ID })
+		switch event.Action {
+		case "submitted":
+			if i < 0 {
+				reviews = append(reviews, review)
+				pr.Timeline = append(pr.Timeline, github.TimelineEvent{Type: "review", Actor: review.User, CreatedAt: review.SubmittedAt})
+			}
+		case "dismissed":
+			if i >= 0 {
+				reviews[i].State = "DISMISSED"
+			}
+		}
+	case event.Event == "pull_request":
+		switch event.Action {
+		case "synchronize":
+			pr.Timeline = append(pr.Timeline, github.TimelineEvent{Type: "commit", Actor: event.Sender, CreatedAt: event.At})
+		case "ready_for_review":
+			pr.Timeline = append(pr.Timeline, github.TimelineEvent{Type: "ready_for_review", Actor: event.Sender, CreatedAt: event.At})
+		case "review_requested":
+			pr.Timeline = append(pr.Timeline, github.TimelineEvent{Type: "review_requested", Actor: event.Sender, CreatedAt: event.At})
+		}
+	}
+
+	pr.Timeline = capTimeline(pr.Timeline)
+	pr.SetReviews(reviews)
+	return &pr
+}
+
+// applyCheck updates the checks of the cached PRs whose head commit is
+// event.SHA; the caller holds the lock. A completed check suite names none
+// of its runs, so the repository is dropped instead and fetched in full by
+// the next run.
+func (c *Cache) applyCheck(key string, cached *cachedRepo, event *github.WebhookEvent) (bool, error) {
+	changed := false
+	for number, prev := range cached.PullRequests {
+		if prev.Head == nil || prev.Head.SHA != event.SHA {
+			continue
+		}
+		if event.Check == nil {
+			delete(c.repos, key)
+			return true, c.save()
+		}
+		pr := *prev
+		pr.Checks = setCheck(prev.Checks, *event.Check)
+		cached.PullRequests[number] = &pr
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, c.save()
+}
+
+// setCheck returns checks with the state of the check named like check
+// replaced, which marks it as reported, or check added. Names are matched ignoring case, like
+// github.checks.required.
+func setCheck(checks []github.Check, check github.Check) []github.Check {
+	checks = slices.Clone(checks)
+	i := slices.IndexFunc(checks, func(c github.Check) bool { return strings.EqualFold(c.Name, check.Name) })
+	if i < 0 {
+		return append(checks, check)
+	}
+	checks[i].State = check.State
+	checks[i].Since = time.Time{}
+	return checks
+}
+
+// pendingRequired returns the required checks of a previous head commit as
+// pending, for a new one pushed at pushed that has not reported yet
+func pendingRequired(checks []github.Check, pushed time.Time) []github.Check {
+	var pending []github.Check
+	for _, check := range checks {
+		if check.Required {
+			check.State = forge.CIPending
+			check.Since = pushed
+			pending = append(pending, check)
+		}
+	}
+	return pending
+}
+
+// timelineLength is the number of events kept per PR, the last 50 like the
+// GraphQL fetcher
+const timelineLength = 50
+
+// capTimeline drops all but the last timelineLength events
+func capTimeline(events []github.TimelineEvent) []github.TimelineEvent {
+	if len(events) <= timelineLength {
+		return events
+	}
+	return slices.Clone(events[len(events)-timelineLength:])
+}
+
+// save persists the cache; the caller holds the lock
+func (c *Cache) save() error {
+	if c.store == nil {
+		return nil
+	}
+	if err := c.store.Put(stateKey, persistedCache{Repos: c.repos, Reconciled: c.reconciled}); err != nil {
+		return fmt.Errorf("failed to persist pull request cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+)
+
+// maxPayloadSize is the largest webhook payload GitHub delivers
+const maxPayloadSize = 25 << 20
+
+// Handler receives GitHub webhooks signed with secret and applies pull
+// request events, comments and check results to cache. Deliveries
+// with a bad signature are rejected with 401; unrelated events are
+// acknowledged and ignored.
+func Handler(secret string, cache *Cache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		delivery := r.Header.Get("X-GitHub-Delivery")
+		r.Body = http.MaxBytesReader(w, r.Body, maxPayloadSize)
+
+		event, err := github.ParseWebhook(r, []byte(secret))
+		switch {
+		case errors.Is(err, github.ErrInvalidSignature):
+			logger.Error("Rejected webhook delivery %s: %v", delivery, err)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		case err != nil:
+			logger.Error("Rejected webhook delivery %s: %v", delivery, err)
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		if event.PullRequest == nil && event.Comment == nil && event.SHA == "" {
+			logger.Debug("Ignoring %s webhook delivery %s", event.Event, delivery)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		log := logger.With(logger.FieldRepo, event.Owner+"/"+event.Repo, logger.FieldPR, event.Number)
+		changed, err := cache.Apply(event)
+		if err != nil {
+			// The in-memory cache is updated; only persisting it failed
+			log.Error("Failed to apply %s webhook delivery %s: %v", event.Event, delivery, err)
+		} else if changed {
+			log.Debug("Applied %s %s webhook to PR #%d", event.Event, event.Action, event.Number)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+)
+
+const testSecret = "s3cret"
+
+func deliver(t *testing.T, handler http.Handler, event, payload, secret string) int {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := &github.PullRequest{Owner: "acme", Repo: "api", Number: 7, State: "open", Additions: 40, Deletions: 2, TotalChanges: 42, SizeCategory: "S"}
+	if err := cache.Replace("acme", []string{"api"}, []*github.PullRequest{existing}); err != nil {
+		t.Fatal(err)
+	}
+	handler := Handler(testSecret, cache)
+
+	repo := `"repository": {"name": "api", "owner": {"login": "acme"}}`
+	review := `{"action": "submitted", "review": {"id": 1, "state": "approved", "user": {"login": "bob"}, "submitted_at": "2024-05-01T10:00:00Z"},
+		"pull_request": {"number": 7, "state": "open", "title": "Fix", "user": {"login": "alice"}}, ` + repo + `}`
+
+	if code := deliver(t, handler, "pull_request_review", review, "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: got status %d, want 401", code)
+	}
+	if code := deliver(t, handler, "pull_request_review", review, testSecret); code != http.StatusAccepted {
+		t.Fatalf("review: got status %d, want 202", code)
+	}
+	// Redelivery of the same review must not count it twice
+	deliver(t, handler, "pull_request_review", review, testSecret)
+
+	opened := `{"action": "opened", "pull_request": {"number": 8, "state": "open", "title": "New", "user": {"login": "carol"}, "additions": 3}, ` + repo + `}`
+	if code := deliver(t, handler, "pull_request", opened, testSecret); code != http.StatusAccepted {
+		t.Fatalf("opened: got status %d, want 202", code)
+	}
+	comment := `{"action": "created", "issue": {"number": 7, "pull_request": {"url": "https://api.github.com/repos/acme/api/pulls/7"}},
+		"comment": {"body": "On it.\n/pr-watcher snooze 2d", "user": {"login": "bob"}, "created_at": "2024-05-01T11:00:00Z"}, ` + repo + `}`
+	if code := deliver(t, handler, "issue_comment", comment, testSecret); code != http.StatusAccepted {
+		t.Fatalf("comment: got status %d, want 202", code)
+	}
+	chat := `{"action": "created", "issue": {"number": 7, "pull_request": {"url": "https://api.github.com/repos/acme/api/pulls/7"}},
+		"comment": {"body": "Looks close, one question below.", "user": {"login": "carol"}, "created_at": "2024-05-01T12:00:00Z"}, ` + repo + `}`
+	deliver(t, handler, "issue_comment", chat, testSecret)
+	reminder := `{"action": "created", "issue": {"number": 7, "pull_request": {"url": "https://api.github.com/repos/acme/api/pulls/7"}},
+		"comment": {"body": "<!-- pr-watcher:reminder -->\n### Needs approval", "user": {"login": "bot"}, "created_at": "2024-05-01T13:00:00Z"}, ` + repo + `}`
+	deliver(t, handler, "issue_comment", reminder, testSecret)
+	if code := deliver(t, handler, "ping", `{"zen": "hi"}`, testSecret); code != http.StatusNoContent {
+		t.Fatalf("ping: got status %d, want 204", code)
+	}
+
+	// Reload from disk to check the updates were persisted
+	store, err = state.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err = NewCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prs, missing := cache.PullRequests("acme", []string{"api", "web"})
+	if len(missing) != 1 || missing[0] != "web" {
+		t.Errorf("missing = %v, want [web]", missing)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("got %d cached PRs, want 2", len(prs))
+	}
+	if pr := prs[0]; !pr.Approved || pr.ReviewCount != 1 || pr.SizeCategory != "S" || pr.Title != "Fix" {
+		t.Errorf("PR #7 = %+v, want approved once with size S kept and title updated", pr)
+	}
+	if timeline := prs[0].Timeline; len(timeline) != 3 || timeline[1].Command != "/pr-watcher snooze 2d" ||
+		timeline[2].Actor != "carol" || timeline[2].Command != "" {
+		t.Errorf("PR #7 timeline = %+v, want the review, the snooze command and carol's comment", timeline)
+	}
+
+	closed := `{"action": "closed", "pull_request": {"number": 8, "state": "closed", "user": {"login": "carol"}}, ` + repo + `}`
+	deliver(t, Handler(testSecret, cache), "pull_request", closed, testSecret)
+	if prs, _ := cache.PullRequests("acme", []string{"api"}); len(prs) != 1 {
+		t.Errorf("got %d cached PRs after close, want 1", len(prs))
+	}
+}
+
+func TestCacheApply_CapsTimeline(t *testing.T) {
+	cache, err := NewCache(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Replace("acme", []string{"api"}, []*github.PullRequest{{Owner: "acme", Repo: "api", Number: 7, State: "open"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < timelineLength+10; i++ {
+		comment := &github.TimelineEvent{Type: "comment", Actor: "bob", CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+		if _, err := cache.Apply(&github.WebhookEvent{Event: "issue_comment", Owner: "acme", Repo: "api", Number: 7, Comment: comment}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prs, _ := cache.PullRequests("acme", []string{"api"})
+	timeline := prs[0].Timeline
+	if len(timeline) != timelineLength || !timeline[len(timeline)-1].CreatedAt.Equal(start.Add(time.Duration(timelineLength+9)*time.Minute)) {
+		t.Errorf("Expected the last %d comments, got %d ending at %v", timelineLength, len(timeline), timeline[len(timeline)-1].CreatedAt)
+	}
+}
+
+func TestHandler_Checks(t *testing.T) {
+	cache, err := NewCache(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := &github.PullRequest{Owner: "acme", Repo: "api", Number: 7, State: "open", Head: &github.Branch{SHA: "abc"},
+		Checks: []github.Check{{Name: "build", State: forge.CIPending, Required: true}}}
+	if err := cache.Replace("acme", []string{"api"}, []*github.PullRequest{existing}); err != nil {
+		t.Fatal(err)
+	}
+	handler := Handler(testSecret, cache)
+	checks := func() []github.Check {
+		t.Helper()
+		prs, _ := cache.PullRequests("acme", []string{"api"})
+		if len(prs) != 1 {
+			t.Fatalf("got %d cached PRs, want 1", len(prs))
+		}
+		return prs[0].Checks
+	}
+
+	repo := `"repository": {"name": "api", "owner": {"login": "acme"}}`
+	run := `{"action": "completed", "check_run": {"name": "Build", "head_sha": "abc", "status": "completed", "conclusion": "success"}, ` + repo + `}`
+	if code := deliver(t, handler, "check_run", run, testSecret); code != http.StatusAccepted {
+		t.Fatalf("check_run: got status %d, want 202", code)
+	}
+	status := `{"sha": "abc", "context": "lint", "state": "failure", ` + repo + `}`
+	deliver(t, handler, "status", status, testSecret)
+	other := `{"sha": "123", "context": "lint", "state": "success", ` + repo + `}`
+	deliver(t, handler, "status", other, testSecret)
+	want := []github.Check{{Name: "build", State: forge.CIPassing, Required: true}, {Name: "lint", State: forge.CIFailing}}
+	if got := checks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("checks = %+v, want %+v", got, want)
+	}
+
+	// A push leaves only the required checks, pending for the new commit
+	pushed := `{"action": "synchronize", "pull_request": {"number": 7, "state": "open", "head": {"sha": "def"}, "user": {"login": "alice"}}, ` + repo + `}`
+	deliver(t, handler, "pull_request", pushed, testSecret)
+	if got := checks(); len(got) != 1 || got[0].Name != "build" || got[0].State != forge.CIPending || got[0].Since.IsZero() {
+		t.Errorf("checks after push = %+v, want build pending since the push", got)
+	}
+	reported := strings.Replace(run, `"head_sha": "abc"`, `"head_sha": "def"`, 1)
+	deliver(t, handler, "check_run", reported, testSecret)
+	if got := checks(); len(got) != 1 || got[0].State != forge.CIPassing || !got[0].Since.IsZero() {
+		t.Errorf("checks after the run reported = %+v, want build passing", got)
+	}
+
+	// A completed check suite names no runs, so the repository is fetched again
+	suite := `{"action": "completed", "check_suite": {"head_sha": "def", "conclusion": "failure"}, ` + repo + `}`
+	deliver(t, handler, "check_suite", suite, testSecret)
+	if _, missing := cache.PullRequests("acme", []string{"api"}); len(missing) != 1 {
+		t.Errorf("missing = %v, want [api] after a completed check suite", missing)
+	}
+}
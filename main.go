@@ -1,55 +1,69 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/jimohabdol/git-pr-watcher/internal/config"
-	"github.com/jimohabdol/git-pr-watcher/internal/github"
 	"github.com/jimohabdol/git-pr-watcher/internal/logger"
 	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+	"github.com/jimohabdol/git-pr-watcher/internal/snooze"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
 	"github.com/jimohabdol/git-pr-watcher/internal/watcher"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+
 	var (
 		configFile = flag.String("config", "config.yaml", "Path to configuration file")
 		watch      = flag.Bool("watch", false, "Run in watch mode (continuous monitoring)")
-		interval   = flag.Duration("interval", 1*time.Hour, "Check interval when in watch mode")
+		interval   = flag.Duration("interval", 0, "Check interval when in watch mode (overrides rules.check_interval and rules.schedule)")
 		debug      = flag.Bool("debug", false, "Enable debug logging")
 		verbose    = flag.Bool("verbose", false, "Enable verbose logging")
 		skipEmails = flag.Bool("skip-emails", false, "Skip sending emails (for testing)")
+		healthAddr = flag.String("health-addr", "", "Address for /healthz and /readyz in watch mode (overrides health.listen)")
 	)
 	flag.Parse()
 
+	applyFlags := func(cfg *config.Config) {
+		if *debug {
+			cfg.Debug.Enabled = true
+		}
+		if *verbose {
+			cfg.Debug.Verbose = true
+		}
+		if *skipEmails {
+			cfg.Debug.SkipEmails = true
+		}
+		if *healthAddr != "" {
+			cfg.Health.Listen = *healthAddr
+		}
+		if *interval > 0 {
+			cfg.Rules.CheckInterval = *interval
+			cfg.Rules.Schedule = ""
+		}
+	}
+
 	cfg, err := config.Load(*configFile)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
-
-	if *debug {
-		cfg.Debug.Enabled = true
-	}
-	if *verbose {
-		cfg.Debug.Verbose = true
-	}
-	if *skipEmails {
-		cfg.Debug.SkipEmails = true
+	applyFlags(cfg)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	var logLevel logger.LogLevel
-	if cfg.Debug.Verbose {
-		logLevel = logger.VERBOSE
-	} else if cfg.Debug.Enabled {
-		logLevel = logger.DEBUG
-	} else {
-		logLevel = logger.INFO
+	if err := logger.InitWithOptions(loggerOptions(cfg)); err != nil {
+		log.Fatalf("Failed to initialize logging: %v", err)
 	}
-	logger.Init(logLevel)
 
 	logger.Info("Starting GitHub PR Age Watcher")
 	logger.Debug("Configuration loaded from: %s", *configFile)
@@ -57,12 +71,17 @@ func main() {
 		logger.Info("Email sending is DISABLED (testing mode)")
 	}
 
-	githubClient, err := github.NewClient(cfg.GitHub.Token)
+	githubClient, err := newGitHubForge(cfg)
+	if err != nil {
+		logger.Error("%v", err)
+		return
+	}
+	sources, err := newSources(cfg.Sources)
 	if err != nil {
-		logger.Error("Failed to create GitHub client: %v", err)
+		logger.Error("Failed to create sources: %v", err)
 		return
 	}
-	logger.Debug("GitHub client initialized")
+	logger.Debug("GitHub client and %d other source(s) initialized", len(sources))
 
 	emailNotifier, err := notifier.NewEmailNotifier(cfg.Email, cfg.Debug.SkipEmails)
 	if err != nil {
@@ -71,40 +90,86 @@ func main() {
 	}
 	logger.Debug("Email notifier initialized")
 
-	prWatcher := watcher.NewPRWatcher(githubClient, emailNotifier, cfg)
-	defer prWatcher.Close()
-
-	if *watch {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-		logger.Info("Starting PR watcher in watch mode (interval: %v)", *interval)
-		logger.Info("Press Ctrl+C to stop gracefully")
+	store, err := state.Open(cfg.State.File)
+	if err != nil {
+		logger.Error("Failed to open state store: %v", err)
+		return
+	}
 
-		ticker := time.NewTicker(*interval)
-		defer ticker.Stop()
+	snoozes, err := snooze.NewStore(store)
+	if err != nil {
+		logger.Error("Failed to load snoozes: %v", err)
+		return
+	}
 
-		if err := prWatcher.CheckPRs(); err != nil {
-			logger.Error("Error checking PRs: %v", err)
-		}
+	prWatcher := watcher.NewPRWatcher(githubClient, sources, emailNotifier, cfg)
+	prWatcher.SetStore(store)
+	prWatcher.SetSnoozes(snoozes)
+	defer prWatcher.Close()
 
-		for {
-			select {
-			case <-sigChan:
-				logger.Info("Received shutdown signal, stopping gracefully...")
-				return
-			case <-ticker.C:
-				if err := prWatcher.CheckPRs(); err != nil {
-					logger.Error("Error checking PRs: %v", err)
-				}
-			}
+	if *watch {
+		w := &watchMode{
+			configFile:    *configFile,
+			applyFlags:    applyFlags,
+			cfg:           cfg,
+			githubClient:  githubClient,
+			sources:       sources,
+			emailNotifier: emailNotifier,
+			prWatcher:     prWatcher,
+			store:         store,
+			snoozes:       snoozes,
 		}
+		w.run()
 	} else {
 		logger.Info("Running PR watcher once...")
-		if err := prWatcher.CheckPRs(); err != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := prWatcher.CheckPRs(ctx); err != nil {
 			logger.Error("Error checking PRs: %v", err)
 			return
 		}
 		logger.Info("PR check completed successfully")
 	}
 }
+
+// runValidate implements the "validate" command: it loads and validates the
+// configuration, prints every problem found and returns the exit code
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configFile := fs.String("config", "config.yaml", "Path to configuration file")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%s: configuration is valid\n", *configFile)
+	return 0
+}
+
+// loggerOptions configures the global logger from the logging and debug
+// sections
+func loggerOptions(cfg *config.Config) logger.Options {
+	logLevel := logger.INFO
+	if cfg.Logging.Level != "" {
+		logLevel = logger.ParseLogLevel(cfg.Logging.Level)
+	}
+	if cfg.Debug.Verbose {
+		logLevel = logger.VERBOSE
+	} else if cfg.Debug.Enabled && logLevel < logger.DEBUG {
+		logLevel = logger.DEBUG
+	}
+	return logger.Options{
+		Level:      logLevel,
+		Format:     cfg.Logging.Format,
+		Output:     cfg.Logging.Output,
+		File:       cfg.Logging.File,
+		MaxSizeMB:  cfg.Logging.MaxSizeMB,
+		MaxBackups: cfg.Logging.MaxBackups,
+	}
+}
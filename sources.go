@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/bitbucket"
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/gitea"
+	"github.com/jimohabdol/git-pr-watcher/internal/github"
+	"github.com/jimohabdol/git-pr-watcher/internal/gitlab"
+)
+
+// newGitHubForge creates the GitHub client, or returns nil when only other
+// sources are configured
+func newGitHubForge(cfg *config.Config) (forge.Forge, error) {
+	if !cfg.GitHub.Configured() && len(cfg.Sources) > 0 {
+		return nil, nil
+	}
+	client, err := github.NewClientForConfig(cfg.GitHub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	return client, nil
+}
+
+// newSources creates a forge for every configured source, keyed by name
+func newSources(cfgs []config.SourceConfig) (map[string]forge.Forge, error) {
+	sources := make(map[string]forge.Forge, len(cfgs))
+	for _, cfg := range cfgs {
+		var (
+			f   forge.Forge
+			err error
+		)
+		switch cfg.Type {
+		case "gitlab":
+			f, err = gitlab.NewClient(cfg)
+		case "gitea", "forgejo":
+			f, err = gitea.NewClient(cfg)
+		case "bitbucket":
+			f, err = bitbucket.NewClient(cfg)
+		default:
+			err = fmt.Errorf("unsupported type %q", cfg.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", cfg.Name, err)
+		}
+		sources[cfg.Name] = f
+	}
+	return sources, nil
+}
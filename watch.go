@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/health"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+	"github.com/jimohabdol/git-pr-watcher/internal/schedule"
+	"github.com/jimohabdol/git-pr-watcher/internal/snooze"
+	"github.com/jimohabdol/git-pr-watcher/internal/state"
+	"github.com/jimohabdol/git-pr-watcher/internal/systemd"
+	"github.com/jimohabdol/git-pr-watcher/internal/watcher"
+	"github.com/jimohabdol/git-pr-watcher/internal/webhook"
+)
+
+// watchMode runs PR checks on a schedule until a shutdown signal arrives and
+// reloads the configuration on SIGHUP or when the config file changes
+type watchMode struct {
+	configFile    string
+	applyFlags    func(*config.Config)
+	cfg           *config.Config
+	githubClient  forge.Forge // nil when only other sources are configured
+	sources       map[string]forge.Forge
+	emailNotifier *notifier.EmailNotifier
+	prWatcher     *watcher.PRWatcher
+	store         *state.Store
+	snoozes       *snooze.Store
+	monitor       *health.Monitor
+	sched         schedule.Schedule
+	configHash    [sha256.Size]byte
+
+	// servers are the HTTP muxes by listen address, so that the health
+	// endpoints and the webhook receiver can share a port
+	servers map[string]*http.ServeMux
+
+	// ctx is canceled on shutdown to stop the run in progress
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running atomic.Bool
+	runs    sync.WaitGroup
+
+	// retired closes what a reload replaced once the run in progress, which
+	// may still use it, has finished
+	retireMu sync.Mutex
+	retired  []func()
+}
+
+func (w *watchMode) run() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	w.monitor = health.NewMonitor(w.cfg.Health.MaxConsecutiveFailures, w.cfg.Health.MaxRunDuration, w.cfg.Health.ProbeTimeout)
+	w.registerChecks()
+
+	if w.cfg.Webhook.Enabled {
+		cache, err := webhook.NewCache(w.store)
+		if err != nil {
+			logger.Error("Failed to load pull request cache: %v", err)
+			return
+		}
+		w.prWatcher.SetCache(cache)
+		w.mux(w.cfg.Webhook.Listen).Handle(w.cfg.Webhook.Path, webhook.Handler(w.cfg.Webhook.Secret, cache))
+		logger.Info("Receiving GitHub webhooks on %s%s", w.cfg.Webhook.Listen, w.cfg.Webhook.Path)
+	}
+	if snoozeCfg := w.cfg.Actions.Snooze; snoozeCfg.Enabled && snoozeCfg.Links.Enabled {
+		w.mux(snoozeCfg.Links.Listen).Handle(snoozeCfg.Links.Path, snooze.Handler(snoozeCfg.Links.Secret, w.snoozes, snoozeCfg.Max))
+		logger.Info("Serving snooze links on %s%s", snoozeCfg.Links.Listen, snoozeCfg.Links.Path)
+	}
+	if w.cfg.Health.Listen != "" {
+		w.monitor.Register(w.mux(w.cfg.Health.Listen))
+		logger.Info("Health endpoints listening on %s", w.cfg.Health.Listen)
+	}
+	defer w.startServers()()
+
+	stopWatchdog := make(chan struct{})
+	defer close(stopWatchdog)
+	startWatchdog(w.monitor, stopWatchdog)
+
+	sched, err := buildSchedule(w.cfg)
+	if err != nil {
+		logger.Error("Invalid schedule: %v", err)
+		return
+	}
+	w.sched = sched
+	w.configHash, _ = hashFile(w.configFile)
+
+	// Runs happen in the background so that a slow run never delays signal
+	// handling; the one in flight is canceled on shutdown
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	defer w.stopRuns()
+
+	if w.cfg.Rules.Schedule != "" {
+		logger.Info("Starting PR watcher in watch mode (schedule: %q)", w.cfg.Rules.Schedule)
+	} else {
+		logger.Info("Starting PR watcher in watch mode (interval: %v)", w.cfg.Rules.CheckInterval)
+	}
+	logger.Info("Press Ctrl+C to stop gracefully")
+
+	if _, err := systemd.Notify("READY=1"); err != nil {
+		logger.Error("Failed to notify systemd: %v", err)
+	}
+
+	if schedule.IsContinuous(w.sched) {
+		w.runCheck()
+	}
+
+	timer := time.NewTimer(w.nextRun())
+	defer timer.Stop()
+
+	poll := time.NewTicker(w.cfg.Reload.PollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-sigChan:
+			logger.Info("Received shutdown signal, stopping gracefully...")
+			_, _ = systemd.Notify("STOPPING=1")
+			return
+		case <-hupChan:
+			logger.Info("Received SIGHUP, reloading configuration from %s", w.configFile)
+			if w.reload() {
+				timer.Reset(w.nextRun())
+				poll.Reset(w.cfg.Reload.PollInterval)
+			}
+		case <-poll.C:
+			if !w.cfg.Reload.WatchFile || !w.configFileChanged() {
+				continue
+			}
+			logger.Info("Configuration file %s changed, reloading", w.configFile)
+			if w.reload() {
+				timer.Reset(w.nextRun())
+				poll.Reset(w.cfg.Reload.PollInterval)
+			}
+		case <-timer.C:
+			w.runCheck()
+			timer.Reset(w.nextRun())
+		}
+	}
+}
+
+// mux returns the mux served on addr, creating it on first use
+func (w *watchMode) mux(addr string) *http.ServeMux {
+	if w.servers == nil {
+		w.servers = make(map[string]*http.ServeMux)
+	}
+	mux, ok := w.servers[addr]
+	if !ok {
+		mux = http.NewServeMux()
+		w.servers[addr] = mux
+	}
+	return mux
+}
+
+// startServers serves every mux and returns a function that shuts them down
+func (w *watchMode) startServers() func() {
+	var servers []*http.Server
+	for addr, mux := range w.servers {
+		server := &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		servers = append(servers, server)
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("HTTP server on %s failed: %v", addr, err)
+			}
+		}()
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, server := range servers {
+			_ = server.Shutdown(ctx)
+		}
+	}
+}
+
+// runCheck starts a PR check in the background. A check that is due while
+// the previous one is still in progress is skipped rather than queued.
+func (w *watchMode) runCheck() {
+	if !w.running.CompareAndSwap(false, true) {
+		logger.Info("Previous PR check is still in progress, skipping this run")
+		return
+	}
+	w.runs.Add(1)
+	go func() {
+		defer w.runs.Done()
+		defer w.runFinished()
+
+		w.monitor.RunStarted()
+		err := w.prWatcher.CheckPRs(w.ctx)
+		w.monitor.RunFinished(err)
+		if err != nil {
+			logger.Error("Error checking PRs: %v", err)
+		}
+	}()
+}
+
+// runFinished marks the run as over and closes what reloads retired during
+// it
+func (w *watchMode) runFinished() {
+	w.retireMu.Lock()
+	w.running.Store(false)
+	retired := w.retired
+	w.retired = nil
+	w.retireMu.Unlock()
+
+	for _, close := range retired {
+		close()
+	}
+}
+
+// retire calls close right away, or once the run in progress finished if
+// there is one
+func (w *watchMode) retire(close func()) {
+	w.retireMu.Lock()
+	defer w.retireMu.Unlock()
+	if w.running.Load() {
+		w.retired = append(w.retired, close)
+		return
+	}
+	close()
+}
+
+// stopRuns cancels the run in progress and waits up to
+// shutdown.grace_period for it to wind down
+func (w *watchMode) stopRuns() {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(w.cfg.Shutdown.GracePeriod):
+		logger.Error("PR check did not stop within %v, exiting anyway", w.cfg.Shutdown.GracePeriod)
+	}
+}
+
+func (w *watchMode) nextRun() time.Duration {
+	next := w.sched.Next(time.Now())
+	if next.IsZero() {
+		logger.Error("Schedule has no upcoming runs")
+		return 24 * time.Hour
+	}
+	wait := time.Until(next) + schedule.Jitter(w.cfg.Rules.Jitter)
+	logger.Debug("Next PR check at %s", time.Now().Add(wait).Format(time.RFC3339))
+	return wait
+}
+
+func (w *watchMode) registerChecks() {
+	w.monitor.RemoveChecks()
+	if w.githubClient != nil {
+		w.monitor.AddCheck("github", w.githubClient.Ping)
+	}
+	for name, source := range w.sources {
+		w.monitor.AddCheck("source:"+name, source.Ping)
+	}
+	w.monitor.AddCheck("smtp", w.emailNotifier.Ping)
+}
+
+// reload loads and validates the configuration file and swaps it in. The
+// GitHub client and email notifier are only rebuilt when their sections
+// changed. On any error the running configuration is kept.
+func (w *watchMode) reload() bool {
+	newCfg, err := config.Load(w.configFile)
+	if err != nil {
+		logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+		return false
+	}
+	w.applyFlags(newCfg)
+	w.configHash, _ = hashFile(w.configFile)
+	if err := newCfg.Validate(); err != nil {
+		logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+		return false
+	}
+
+	changes := config.Diff(w.cfg, newCfg)
+	if len(changes) == 0 {
+		logger.Info("Configuration reloaded, no changes")
+		return false
+	}
+
+	sched, err := buildSchedule(newCfg)
+	if err != nil {
+		logger.Error("Configuration reload failed, keeping current configuration: invalid schedule: %v", err)
+		return false
+	}
+
+	githubClient := w.githubClient
+	if config.SectionChanged(w.cfg, newCfg, "github") || config.SectionChanged(w.cfg, newCfg, "sources") {
+		githubClient, err = newGitHubForge(newCfg)
+		if err != nil {
+			logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+			return false
+		}
+	}
+
+	sources := w.sources
+	if config.SectionChanged(w.cfg, newCfg, "sources") {
+		sources, err = newSources(newCfg.Sources)
+		if err != nil {
+			logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+			return false
+		}
+	}
+
+	emailNotifier := w.emailNotifier
+	if config.SectionChanged(w.cfg, newCfg, "email") || w.cfg.Debug.SkipEmails != newCfg.Debug.SkipEmails {
+		emailNotifier, err = notifier.NewEmailNotifier(newCfg.Email, newCfg.Debug.SkipEmails)
+		if err != nil {
+			logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+			return false
+		}
+	}
+
+	var oldLogger *logger.Logger
+	if config.SectionChanged(w.cfg, newCfg, "logging") || config.SectionChanged(w.cfg, newCfg, "debug") {
+		oldLogger, err = logger.Replace(loggerOptions(newCfg))
+		if err != nil {
+			logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+			if emailNotifier != w.emailNotifier {
+				emailNotifier.Close()
+			}
+			return false
+		}
+	}
+
+	if config.SectionChanged(w.cfg, newCfg, "health") {
+		logger.Info("Health settings changed; a restart is required for them to take effect")
+	}
+	if config.SectionChanged(w.cfg, newCfg, "webhook") || config.SectionChanged(w.cfg, newCfg, "state") {
+		logger.Info("Webhook or state settings changed; a restart is required for them to take effect")
+	}
+	if config.SectionChanged(w.cfg, newCfg, "actions.snooze.links") {
+		logger.Info("Snooze link settings changed; a restart is required for the link server to pick them up")
+	}
+
+	w.prWatcher.Reload(newCfg, githubClient, sources, emailNotifier)
+	if oldNotifier := w.emailNotifier; oldNotifier != emailNotifier {
+		w.retire(oldNotifier.Close)
+	}
+	if oldLogger != nil {
+		w.retire(func() { _ = oldLogger.Close() })
+	}
+	w.cfg = newCfg
+	w.githubClient = githubClient
+	w.sources = sources
+	w.emailNotifier = emailNotifier
+	w.sched = sched
+	w.registerChecks()
+
+	logger.Info("Configuration reloaded with %d change(s):", len(changes))
+	for _, change := range changes {
+		logger.Info("  %s", change)
+	}
+	return true
+}
+
+// configFileChanged reports whether the config file content differs from
+// the last time it was loaded
+func (w *watchMode) configFileChanged() bool {
+	hash, err := hashFile(w.configFile)
+	if err != nil {
+		return false
+	}
+	return !bytes.Equal(hash[:], w.configHash[:])
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+func buildSchedule(cfg *config.Config) (schedule.Schedule, error) {
+	sched, err := schedule.New(schedule.Options{
+		Cron:        cfg.Rules.Schedule,
+		Interval:    cfg.Rules.CheckInterval,
+		ActiveHours: cfg.Rules.ActiveHours,
+		ActiveDays:  cfg.Rules.ActiveDays,
+		Timezone:    cfg.Rules.Timezone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build schedule: %w", err)
+	}
+	return sched, nil
+}
+
+// startWatchdog pings the systemd watchdog at half the configured timeout for
+// as long as the watch loop is alive. When a run gets stuck or keeps failing
+// the pings stop and systemd restarts the service.
+func startWatchdog(monitor *health.Monitor, stop <-chan struct{}) {
+	timeout, ok := systemd.WatchdogInterval()
+	if !ok {
+		return
+	}
+	logger.Debug("systemd watchdog enabled (timeout: %v)", timeout)
+
+	go func() {
+		ticker := time.NewTicker(timeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if !monitor.Alive() {
+					logger.Error("Watch loop is not healthy, withholding systemd watchdog ping")
+					continue
+				}
+				if _, err := systemd.Notify("WATCHDOG=1"); err != nil {
+					logger.Error("Failed to ping systemd watchdog: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jimohabdol/git-pr-watcher/internal/config"
+	"github.com/jimohabdol/git-pr-watcher/internal/forge"
+	"github.com/jimohabdol/git-pr-watcher/internal/health"
+	"github.com/jimohabdol/git-pr-watcher/internal/logger"
+	"github.com/jimohabdol/git-pr-watcher/internal/notifier"
+	"github.com/jimohabdol/git-pr-watcher/internal/watcher"
+)
+
+// blockingForge holds GetPullRequests until release is closed
+type blockingForge struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingForge) GetPullRequests(ctx context.Context, owner string, repos []string) ([]*forge.PullRequest, error) {
+	close(f.started)
+	<-f.release
+	return nil, nil
+}
+
+func (f *blockingForge) GetPRDetails(ctx context.Context, owner, repo string, number int) (*forge.PullRequest, error) {
+	return nil, nil
+}
+
+func (f *blockingForge) ListRepositories(ctx context.Context, owner string) ([]*forge.Repository, error) {
+	return nil, nil
+}
+
+func (f *blockingForge) Ping(ctx context.Context) error {
+	return nil
+}
+
+const watchTestConfig = `
+github:
+  token: "t"
+  owner: "acme"
+  repos: ["api"]
+email:
+  smtp_host: "smtp.example.com"
+  smtp_port: 587
+  from: "watcher@example.com"
+  to: ["team@example.com"]
+  subject: "%s"
+logging:
+  output: "file"
+  file: "%s"
+debug:
+  skip_emails: true
+`
+
+func TestWatchMode_ReloadDuringRun(t *testing.T) {
+	dir := t.TempDir()
+	oldLog, newLog := filepath.Join(dir, "old.log"), filepath.Join(dir, "new.log")
+	configFile := filepath.Join(dir, "config.yaml")
+	writeConfig := func(subject, logFile string) {
+		t.Helper()
+		content := strings.Replace(strings.Replace(watchTestConfig, "%s", subject, 1), "%s", logFile, 1)
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig("PR Alert", oldLog)
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.InitWithOptions(loggerOptions(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	oldLogger := logger.Get()
+	emailNotifier, err := notifier.NewEmailNotifier(cfg.Email, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	github := &blockingForge{started: make(chan struct{}), release: make(chan struct{})}
+
+	w := &watchMode{
+		configFile:    configFile,
+		applyFlags:    func(*config.Config) {},
+		cfg:           cfg,
+		githubClient:  github,
+		emailNotifier: emailNotifier,
+		prWatcher:     watcher.NewPRWatcher(github, nil, emailNotifier, cfg),
+		monitor:       health.NewMonitor(0, 0, time.Second),
+		ctx:           context.Background(),
+	}
+	t.Cleanup(func() { _ = logger.InitWithOptions(logger.Options{Level: logger.INFO}) })
+
+	w.runCheck()
+	<-github.started
+
+	writeConfig("PR Digest", newLog)
+	if !w.reload() {
+		t.Fatal("Expected the reload to apply the changed configuration")
+	}
+	if w.emailNotifier == emailNotifier {
+		t.Error("Expected a new email notifier for the changed email section")
+	}
+	// The run in progress still logs through the old logger and notifier,
+	// so neither may be closed yet
+	if err := emailNotifier.SendApprovalReminder(context.Background(), &forge.PullRequest{Number: 1}, time.Hour, time.Hour); err != nil {
+		t.Errorf("Old notifier closed while the run was in progress: %v", err)
+	}
+
+	close(github.release)
+	w.runs.Wait()
+
+	oldLogger.Info("after the run")
+	old, err := os.ReadFile(oldLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(old), "Found 0 open pull requests") {
+		t.Errorf("Expected the run to finish logging to the old log file, got:\n%s", old)
+	}
+	if strings.Contains(string(old), "after the run") {
+		t.Error("Expected the old log file to be closed once the run finished")
+	}
+	if err := emailNotifier.SendApprovalReminder(context.Background(), &forge.PullRequest{Number: 1}, time.Hour, time.Hour); err == nil {
+		t.Error("Expected the old notifier to be closed once the run finished")
+	}
+
+	logged, err := os.ReadFile(newLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range []string{
+		"Configuration reloaded with 2 change(s)",
+		"email.subject: PR Alert -> PR Digest",
+		"logging.file: " + oldLog + " -> " + newLog,
+	} {
+		if !strings.Contains(string(logged), change) {
+			t.Errorf("Expected %q in the new log file, got:\n%s", change, logged)
+		}
+	}
+}